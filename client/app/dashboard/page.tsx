@@ -22,7 +22,7 @@ import { useEffect, useMemo } from "react";
 // --- WebSocket Connection Logic ---
 // This could be moved to a custom hook (useWebSocket) for even cleaner code
 const useWebSocketConnection = () => {
-  const { updateCrawl } = useCrawlStore();
+  const { updateCrawl, removeCrawls } = useCrawlStore();
 
   useEffect(() => {
     let ws: WebSocket | null = null;
@@ -40,10 +40,21 @@ const useWebSocketConnection = () => {
 
         ws.onmessage = (event) => {
           try {
-            const rawData = JSON.parse(event.data);
-            console.log("WebSocket received raw data:", rawData);
-            console.log("WebSocket data keys:", Object.keys(rawData));
-            updateCrawl(rawData); // Pass raw data, let store normalize it
+            // Every message is an event envelope: { type, version, id, timestamp, payload }
+            const message = JSON.parse(event.data);
+            console.log("WebSocket received event:", message.type, message.id);
+            switch (message.type) {
+              case "crawl.created":
+              case "crawl.status_changed":
+                updateCrawl(message.payload.crawl); // Pass raw data, let store normalize it
+                break;
+              case "crawl.deleted":
+                removeCrawls(message.payload.crawl_ids);
+                break;
+              default:
+                // Progress and batch events aren't shown on the dashboard yet
+                break;
+            }
           } catch (error) {
             console.error("Failed to parse WebSocket message:", error);
           }
@@ -81,7 +92,7 @@ const useWebSocketConnection = () => {
         ws.close(1000, "Component unmounting");
       }
     };
-  }, [updateCrawl]);
+  }, [updateCrawl, removeCrawls]);
 };
 
 export default function Page() {
@@ -178,7 +178,11 @@ export const useCrawlStore = create<CrawlState>((set, get) => ({
   // Action to add a new crawl (from the form submission)
   addCrawl: (rawCrawl) => {
     const crawl = normalizeCrawlData(rawCrawl);
-    const newCrawls = [crawl, ...get().crawls];
+    // The crawl may already have arrived over the WebSocket
+    const newCrawls = [
+      crawl,
+      ...get().crawls.filter((c) => c.ID !== crawl.ID),
+    ];
     set({
       crawls: newCrawls,
       stats: calculateStats(newCrawls),
@@ -8,9 +8,11 @@ import (
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/config"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/database"
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/ratelimit"
 	infra_repo "github.com/diabahmed/sykell-crawler/internal/infrastructure/repository"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/websockets"
 	"github.com/diabahmed/sykell-crawler/internal/presentation/http/router"
+	"gorm.io/gorm"
 )
 
 // @title Web Crawler API
@@ -45,17 +47,84 @@ func main() {
 	// 3. Initialize Infrastructure Dependencies
 	userRepo := infra_repo.NewGormUserRepository(db)
 	crawlRepo := infra_repo.NewGormCrawlRepository(db)
+	crawlLinkRepo := infra_repo.NewGormCrawlLinkRepository(db)
+	crawlPageRepo := infra_repo.NewGormCrawlPageRepository(db)
+	crawlBatchRepo := infra_repo.NewGormCrawlBatchRepository(db)
+	usageRepo := infra_repo.NewGormUsageRepository(db)
 	tokenManager := auth.NewJWTManager(cfg.TokenSymmetricKey, cfg.AccessTokenDuration)
-	crawlerEngine := crawler.NewWebCrawler()
+	hostLimiter := crawler.NewHostLimiter(crawler.LimiterConfig{
+		MaxInFlight:        cfg.CrawlerMaxInFlight,
+		PerHostQPS:         cfg.CrawlerPerHostQPS,
+		PerHostConcurrency: cfg.CrawlerPerHostConcurrency,
+		MaxBackoff:         cfg.CrawlerMaxBackoff,
+	})
+	networkPolicy, err := crawler.ParseNetworkPolicy(cfg.NetworkAllowCIDRs, cfg.NetworkDenyCIDRs)
+	if err != nil {
+		log.Fatalf("invalid network policy: %v", err)
+	}
+	proxies, err := crawler.ParseProxyURLs(cfg.ProxyURLs)
+	if err != nil {
+		log.Fatalf("invalid proxy configuration: %v", err)
+	}
+	crawlerConfig := crawler.Config{
+		UserAgent:        cfg.CrawlerUserAgent,
+		RequestTimeout:   cfg.CrawlerRequestTimeout,
+		PageTimeout:      cfg.CrawlerPageTimeout,
+		Timeout:          cfg.CrawlerCrawlTimeout,
+		Delay:            cfg.CrawlerDelay,
+		Parallelism:      cfg.CrawlerParallelism,
+		LinkCheckWorkers: cfg.CrawlerLinkCheckWorkers,
+		Retry: crawler.RetryPolicy{
+			MaxAttempts:          cfg.RetryMaxAttempts,
+			InitialBackoff:       cfg.RetryInitialBackoff,
+			MaxBackoff:           cfg.RetryMaxBackoff,
+			Multiplier:           cfg.RetryMultiplier,
+			Jitter:               cfg.RetryJitter,
+			RetryableStatusCodes: cfg.RetryStatusCodes,
+			RetryableErrors:      cfg.RetryErrorClasses,
+		},
+		Network:   networkPolicy,
+		Proxies:   proxies,
+		ProxyMode: crawler.ProxyMode(cfg.ProxyDefaultMode),
+		Limits: crawler.BodyLimits{
+			MaxBodySize:         cfg.CrawlerMaxBodySize,
+			MaxDecompressedSize: cfg.CrawlerMaxDecompressedSize,
+			MaxLinksPerPage:     cfg.CrawlerMaxLinksPerPage,
+			MaxDOMElements:      cfg.CrawlerMaxDOMElements,
+		},
+		SiteMaxPages: cfg.CrawlerSiteMaxPages,
+		SiteMaxDepth: cfg.CrawlerSiteMaxDepth,
+		Traps: crawler.TrapLimits{
+			MaxURLLength:       cfg.CrawlerTrapMaxURLLength,
+			MaxSegmentRepeats:  cfg.CrawlerTrapMaxSegmentRepeats,
+			MaxQueryVariants:   cfg.CrawlerTrapMaxQueryVariants,
+			MaxPagesPerPattern: cfg.CrawlerTrapMaxPagesPerPattern,
+		},
+	}
+	crawlerEngine := crawler.NewWebCrawler(crawlerConfig, newLinkCache(cfg, db), hostLimiter)
 	hub := websockets.NewHub() // CREATE THE HUB
 	go hub.Run()               // RUN THE HUB IN A BACKGROUND GOROUTINE
 
 	// 4. Initialize Application Services (injecting dependencies)
 	userService := service.NewUserService(userRepo)
-	crawlService := service.NewCrawlService(crawlRepo, crawlerEngine, hub)
+	quota := service.Quota{
+		MaxConcurrentCrawls: cfg.QuotaMaxConcurrentCrawls,
+		DailyCrawls:         cfg.QuotaDailyCrawls,
+		DailyLinkChecks:     cfg.QuotaDailyLinkChecks,
+		MaxPagesPerCrawl:    cfg.QuotaMaxPagesPerCrawl,
+	}
+	crawlService := service.NewCrawlService(crawlRepo, crawlLinkRepo, crawlPageRepo, crawlBatchRepo, usageRepo, crawlerEngine, hub, cfg.CredentialsEncryptionKey, quota, cfg.CrawlerWorkers)
 
 	// 5. Setup Presentation Layer (Router)
-	router := router.NewRouter(userService, crawlService, tokenManager, hub)
+	rateLimits := router.RateLimits{
+		Auth:   ratelimit.Limit{Requests: cfg.RateLimitAuthRequests, Window: cfg.RateLimitAuthWindow},
+		Submit: ratelimit.Limit{Requests: cfg.RateLimitSubmitRequests, Window: cfg.RateLimitSubmitWindow},
+		API:    ratelimit.Limit{Requests: cfg.RateLimitAPIRequests, Window: cfg.RateLimitAPIWindow},
+	}
+	router, err := router.NewRouter(userService, crawlService, tokenManager, hub, ratelimit.NewMemoryStore(), rateLimits, cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("failed to set up router: %v", err)
+	}
 
 	// 6. Start the HTTP Server
 	log.Printf("Starting server on %s", cfg.ServerAddress)
@@ -63,3 +132,22 @@ func main() {
 		log.Fatalf("failed to start server: %v", err)
 	}
 }
+
+// newLinkCache creates the link status cache selected in the configuration.
+func newLinkCache(cfg config.Config, db *gorm.DB) crawler.LinkCache {
+	ttls := crawler.CacheTTLs{
+		Success:     cfg.LinkCacheTTLSuccess,
+		ClientError: cfg.LinkCacheTTLClientError,
+		ServerError: cfg.LinkCacheTTLServerError,
+		Error:       cfg.LinkCacheTTLNetworkError,
+	}
+
+	if cfg.LinkCacheDriver == "database" {
+		cache, err := crawler.NewDBLinkCache(db, cfg.LinkCacheMaxEntries, ttls)
+		if err != nil {
+			log.Fatalf("failed to initialize link cache: %v", err)
+		}
+		return cache
+	}
+	return crawler.NewMemoryLinkCache(cfg.LinkCacheMaxEntries, ttls)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
+)
+
+// crawlFlights lets concurrent crawls with the same result key share a single execution, so
+// that identical crawls submitted together fetch the page and check its links only once.
+type crawlFlights struct {
+	mu      sync.Mutex
+	flights map[string]*crawlFlight
+}
+
+// crawlFlight is a crawl in progress whose result is shared by everyone waiting for it.
+type crawlFlight struct {
+	done      chan struct{}
+	mu        sync.Mutex
+	progress  []crawler.ProgressFunc // Of the crawl running and of those waiting; nil once one stops waiting
+	info      *crawler.PageInfo
+	shareable bool
+	err       error
+}
+
+func newCrawlFlights() *crawlFlights {
+	return &crawlFlights{flights: make(map[string]*crawlFlight)}
+}
+
+// crawlFunc runs a crawl reporting its progress to progress. It returns whether its result may be
+// shared, which it may not when it depends on the crawl's owner, such as when the owner's link
+// check quota cut it short, or when the crawl was cancelled.
+type crawlFunc func(progress crawler.ProgressFunc) (info *crawler.PageInfo, shareable bool, err error)
+
+// do runs crawl unless a crawl with the same key is already running, in which case it waits for
+// that one and returns its result, reporting it as shared. The running crawl's progress goes to
+// everyone waiting for it. When its result can't be shared, crawl runs on its own after all.
+// Waiting stops when ctx is cancelled. An empty key is never shared.
+func (f *crawlFlights) do(ctx context.Context, key string, progress crawler.ProgressFunc, crawl crawlFunc) (*crawler.PageInfo, bool, error) {
+	if key == "" {
+		info, _, err := crawl(progress)
+		return info, false, err
+	}
+
+	f.mu.Lock()
+	if flight, ok := f.flights[key]; ok {
+		follower := flight.follow(progress)
+		f.mu.Unlock()
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			flight.unfollow(follower)
+			return nil, false, ctx.Err()
+		}
+		if flight.shareable {
+			return flight.info, true, flight.err
+		}
+		info, _, err := crawl(progress)
+		return info, false, err
+	}
+	flight := &crawlFlight{done: make(chan struct{})}
+	flight.follow(progress)
+	f.flights[key] = flight
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.flights, key)
+		f.mu.Unlock()
+		close(flight.done)
+	}()
+	flight.info, flight.shareable, flight.err = crawl(flight.report)
+	return flight.info, false, flight.err
+}
+
+// follow adds a progress function to the flight and returns its index.
+func (f *crawlFlight) follow(progress crawler.ProgressFunc) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.progress = append(f.progress, progress)
+	return len(f.progress) - 1
+}
+
+// unfollow stops reporting progress to the function at index i.
+func (f *crawlFlight) unfollow(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.progress[i] = nil
+}
+
+// report is the crawler.ProgressFunc of the running crawl.
+func (f *crawlFlight) report(p crawler.Progress) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, progress := range f.progress {
+		if progress != nil {
+			progress(p)
+		}
+	}
+}
+
+// resultKey identifies the result of crawling targetURL with opts: crawls with the same key
+// produce the same result, so they can share an execution or reuse a recent result. Crawls
+// with credentials or a dedicated proxy depend on more than their settings and get no key.
+func (s *crawlService) resultKey(targetURL string, opts crawler.Options) string {
+	if opts.Auth != nil || opts.ProxyURL != nil {
+		return ""
+	}
+	normalized, err := opts.Normalizer.Normalize(targetURL)
+	if err != nil {
+		normalized = targetURL
+	}
+	settingsJSON, err := json.Marshal(s.crawler.EffectiveSettings(opts))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(normalized+"\n"), settingsJSON...))
+	return hex.EncodeToString(sum[:])
+}
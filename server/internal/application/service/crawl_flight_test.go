@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
+)
+
+// startLeader runs a crawl under key that reports progress and blocks until release is closed.
+func startLeader(f *crawlFlights, key string, shareable bool, release chan struct{}) {
+	running := make(chan struct{})
+	go f.do(context.Background(), key, nil, func(progress crawler.ProgressFunc) (*crawler.PageInfo, bool, error) {
+		close(running)
+		<-release
+		progress(crawler.Progress{Phase: "done"})
+		return &crawler.PageInfo{Title: "leader"}, shareable, nil
+	})
+	<-running
+}
+
+func TestCrawlFlightsShareResultAndProgress(t *testing.T) {
+	f := newCrawlFlights()
+	release := make(chan struct{})
+	startLeader(f, "key", true, release)
+
+	var phases []string
+	result := make(chan *crawler.PageInfo)
+	go func() {
+		info, shared, _ := f.do(context.Background(), "key", func(p crawler.Progress) { phases = append(phases, p.Phase) }, func(crawler.ProgressFunc) (*crawler.PageInfo, bool, error) {
+			t.Error("follower ran its own crawl")
+			return nil, false, nil
+		})
+		if !shared {
+			t.Error("follower result not reported as shared")
+		}
+		result <- info
+	}()
+	time.Sleep(10 * time.Millisecond) // Let the follower join
+	close(release)
+	if info := <-result; info == nil || info.Title != "leader" {
+		t.Errorf("follower got %+v, want the leader's result", info)
+	}
+	if len(phases) != 1 {
+		t.Errorf("follower got progress %v, want the leader's", phases)
+	}
+}
+
+func TestCrawlFlightsRunUnshareableResultAgain(t *testing.T) {
+	f := newCrawlFlights()
+	release := make(chan struct{})
+	startLeader(f, "key", false, release)
+
+	result := make(chan *crawler.PageInfo)
+	go func() {
+		info, shared, _ := f.do(context.Background(), "key", nil, func(crawler.ProgressFunc) (*crawler.PageInfo, bool, error) {
+			return &crawler.PageInfo{Title: "follower"}, true, nil
+		})
+		if shared {
+			t.Error("follower result reported as shared")
+		}
+		result <- info
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if info := <-result; info == nil || info.Title != "follower" {
+		t.Errorf("follower got %+v, want its own result", info)
+	}
+}
+
+func TestCrawlFlightsFollowerStopsWaitingWhenCancelled(t *testing.T) {
+	f := newCrawlFlights()
+	release := make(chan struct{})
+	defer close(release)
+	startLeader(f, "key", true, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := f.do(ctx, "key", nil, func(crawler.ProgressFunc) (*crawler.PageInfo, bool, error) {
+		t.Error("cancelled follower ran its own crawl")
+		return nil, false, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want %v", err, context.Canceled)
+	}
+}
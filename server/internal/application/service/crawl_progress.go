@@ -0,0 +1,46 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
+)
+
+// progressInterval is the least time between two progress events of a crawl; a new phase is
+// always reported right away.
+const progressInterval = time.Second
+
+// progressReporter returns the function that turns the progress of a crawl into events for its
+// owner, at most one per progressInterval unless the phase changes.
+func (s *crawlService) progressReporter(crawlRecord *entity.Crawl) crawler.ProgressFunc {
+	var mu sync.Mutex
+	var lastPhase string
+	var lastSent time.Time
+	return func(p crawler.Progress) {
+		mu.Lock()
+		now := time.Now()
+		if p.Phase == lastPhase && now.Sub(lastSent) < progressInterval {
+			mu.Unlock()
+			return
+		}
+		lastPhase, lastSent = p.Phase, now
+		mu.Unlock()
+
+		payload := CrawlProgressPayload{
+			CrawlID:      crawlRecord.ID,
+			Phase:        p.Phase,
+			LinksChecked: p.LinksChecked,
+			LinksTotal:   p.LinksTotal,
+			BrokenLinks:  p.BrokenLinks,
+			PagesVisited: p.PagesVisited,
+			ElapsedMs:    p.Elapsed.Milliseconds(),
+		}
+		if p.ETA > 0 {
+			eta := p.ETA.Milliseconds()
+			payload.EtaMs = &eta
+		}
+		s.notify(crawlRecord.UserID, EventCrawlProgress, payload)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+)
+
+// Crawl priorities. Crawls someone is waiting for are interactive; sitemap and batch crawls are
+// bulk by default, so that they don't hold up the crawls submitted one at a time.
+const (
+	PriorityInteractive = "interactive"
+	PriorityBulk        = "bulk"
+)
+
+// priorities lists the priorities from the highest down.
+var priorities = []string{PriorityInteractive, PriorityBulk}
+
+// crawlQueue runs queued crawls on a fixed number of workers. Workers always take the oldest crawl
+// of the highest priority, so bulk crawls only run while no interactive crawl is waiting.
+type crawlQueue struct {
+	mu      sync.Mutex
+	ready   *sync.Cond
+	pending map[string][]*entity.Crawl // Queued crawls per priority, oldest first
+}
+
+// newCrawlQueue starts workers that each run one queued crawl at a time.
+func newCrawlQueue(workers int, run func(*entity.Crawl)) *crawlQueue {
+	q := &crawlQueue{pending: make(map[string][]*entity.Crawl)}
+	q.ready = sync.NewCond(&q.mu)
+	for range max(workers, 1) {
+		go func() {
+			for {
+				run(q.next())
+			}
+		}()
+	}
+	return q
+}
+
+// push queues a crawl; crawls with an unknown priority are queued as bulk.
+func (q *crawlQueue) push(crawl *entity.Crawl) {
+	priority := crawl.Priority
+	if priority != PriorityInteractive {
+		priority = PriorityBulk
+	}
+	q.mu.Lock()
+	q.pending[priority] = append(q.pending[priority], crawl)
+	q.mu.Unlock()
+	q.ready.Signal()
+}
+
+// next blocks until a crawl is queued and removes the one to run next.
+func (q *crawlQueue) next() *entity.Crawl {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		for _, priority := range priorities {
+			if crawls := q.pending[priority]; len(crawls) > 0 {
+				crawl := crawls[0]
+				crawls[0] = nil // Don't keep the crawl alive through the backing array
+				q.pending[priority] = crawls[1:]
+				return crawl
+			}
+		}
+		q.ready.Wait()
+	}
+}
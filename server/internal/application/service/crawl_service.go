@@ -1,19 +1,63 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
 	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
+	"github.com/diabahmed/sykell-crawler/internal/shared/utils"
+	"gorm.io/gorm"
 )
 
+// ErrCredentialsUnavailable is returned when a crawl carries credentials but the server has
+// no key to encrypt them with.
+var ErrCredentialsUnavailable = errors.New("authenticated crawls are disabled: no credentials encryption key configured")
+
+// ErrNotSiteCrawl is returned when site results such as a sitemap are requested for a single-page crawl.
+var ErrNotSiteCrawl = errors.New("crawl is not a site crawl")
+
+// ErrCrawlNotCompleted is returned when results are requested for a crawl that hasn't completed.
+var ErrCrawlNotCompleted = errors.New("crawl has not completed")
+
+// ErrBatchTooLarge is returned when a batch lists more URLs than a single batch may start.
+var ErrBatchTooLarge = fmt.Errorf("a batch may list at most %d URLs", maxBatchRows)
+
+// maxSitemapCrawls caps the crawls started for a single submitted sitemap.
+const maxSitemapCrawls = 1000
+
+// maxBatchRows caps the URLs of a single batch.
+const maxBatchRows = 5000
+
+// CrawlGraph is the internal link graph of a completed site crawl.
+type CrawlGraph struct {
+	Pages   []entity.CrawlPage
+	Edges   []entity.CrawlEdge
+	Orphans []string // Sitemap URLs no crawled page links to
+}
+
 type CrawlService interface {
-	StartCrawl(ctx context.Context, userID uint, targetURL string) (*entity.Crawl, error)
+	StartCrawl(ctx context.Context, userID uint, targetURL string, opts entity.CrawlOptions, creds *entity.CrawlCredentials, reuseWithin time.Duration) (*entity.Crawl, error)
+	StartSitemapCrawls(ctx context.Context, userID uint, sitemapURL string, opts entity.CrawlOptions, creds *entity.CrawlCredentials) ([]*entity.Crawl, int, error)
+	StartBatch(ctx context.Context, userID uint, batch *entity.CrawlBatch, rows []entity.BatchRow, opts entity.CrawlOptions, creds *entity.CrawlCredentials) error
+	GetBatch(ctx context.Context, batchID, userID uint) (*entity.CrawlBatch, map[string]int64, error)
+	GetUsage(ctx context.Context, userID uint) (*Usage, error)
 	GetCrawlHistory(ctx context.Context, userID uint) ([]entity.Crawl, error)
 	GetCrawlResult(ctx context.Context, crawlID, userID uint) (*entity.Crawl, error)
+	GetCrawlLinks(ctx context.Context, crawlID, userID uint, filter repository.CrawlLinkFilter) ([]entity.CrawlLink, int64, error)
+	GetSitemapPages(ctx context.Context, crawlID, userID uint) ([]entity.CrawlPage, error)
+	GetCrawlGraph(ctx context.Context, crawlID, userID uint) (*CrawlGraph, error)
 	RerunCrawl(ctx context.Context, crawlID uint, userID uint) (*entity.Crawl, error)
 	DeleteCrawl(ctx context.Context, crawlID, userID uint) error
 	DeleteCrawlsBulk(ctx context.Context, crawlIDs []uint, userID uint) error
@@ -21,16 +65,37 @@ type CrawlService interface {
 
 type crawlService struct {
 	crawlRepo repository.CrawlRepository
+	linkRepo  repository.CrawlLinkRepository
+	pageRepo  repository.CrawlPageRepository
+	batchRepo repository.CrawlBatchRepository
+	usageRepo repository.UsageRepository
 	crawler   *crawler.WebCrawler
 	notifier  Notifier
+	credsKey  string // Encrypts crawl credentials at rest; empty disables authenticated crawls
+	flights   *crawlFlights
+	queue     *crawlQueue
+	quota     Quota
+
+	runningMu sync.Mutex
+	running   map[uint]context.CancelFunc // Cancels the crawls being performed, by ID
 }
 
-func NewCrawlService(repo repository.CrawlRepository, crawler *crawler.WebCrawler, notifier Notifier) CrawlService {
-	return &crawlService{
+func NewCrawlService(repo repository.CrawlRepository, linkRepo repository.CrawlLinkRepository, pageRepo repository.CrawlPageRepository, batchRepo repository.CrawlBatchRepository, usageRepo repository.UsageRepository, crawler *crawler.WebCrawler, notifier Notifier, credentialsKey string, quota Quota, workers int) CrawlService {
+	s := &crawlService{
 		crawlRepo: repo,
+		linkRepo:  linkRepo,
+		pageRepo:  pageRepo,
+		batchRepo: batchRepo,
+		usageRepo: usageRepo,
 		crawler:   crawler,
 		notifier:  notifier,
+		credsKey:  credentialsKey,
+		flights:   newCrawlFlights(),
+		quota:     quota,
+		running:   make(map[uint]context.CancelFunc),
 	}
+	s.queue = newCrawlQueue(workers, s.performCrawl)
+	return s
 }
 
 func (s *crawlService) GetCrawlHistory(ctx context.Context, userID uint) ([]entity.Crawl, error) {
@@ -41,80 +106,818 @@ func (s *crawlService) GetCrawlResult(ctx context.Context, crawlID, userID uint)
 	return s.crawlRepo.FindByID(ctx, crawlID, userID)
 }
 
-func (s *crawlService) StartCrawl(ctx context.Context, userID uint, targetURL string) (*entity.Crawl, error) {
+// GetCrawlLinks returns a filtered page of the links found by a crawl owned by the user.
+func (s *crawlService) GetCrawlLinks(ctx context.Context, crawlID, userID uint, filter repository.CrawlLinkFilter) ([]entity.CrawlLink, int64, error) {
+	// Verify ownership first; links themselves don't carry the user ID.
+	if _, err := s.crawlRepo.FindByID(ctx, crawlID, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.linkRepo.FindByCrawlID(ctx, crawlID, filter)
+}
+
+// GetSitemapPages returns the pages a generated sitemap lists: the indexable pages found by a
+// completed site crawl owned by the user.
+func (s *crawlService) GetSitemapPages(ctx context.Context, crawlID, userID uint) ([]entity.CrawlPage, error) {
+	if _, err := s.completedSiteCrawl(ctx, crawlID, userID); err != nil {
+		return nil, err
+	}
+	return s.pageRepo.FindIndexable(ctx, crawlID)
+}
+
+// GetCrawlGraph returns the pages, links and orphan pages of a completed site crawl owned by the user.
+func (s *crawlService) GetCrawlGraph(ctx context.Context, crawlID, userID uint) (*CrawlGraph, error) {
+	crawl, err := s.completedSiteCrawl(ctx, crawlID, userID)
+	if err != nil {
+		return nil, err
+	}
+	pages, edges, err := s.pageRepo.FindGraph(ctx, crawlID)
+	if err != nil {
+		return nil, err
+	}
+	graph := &CrawlGraph{Pages: pages, Edges: edges, Orphans: []string{}}
+	if len(crawl.OrphanPages) > 0 {
+		_ = json.Unmarshal(crawl.OrphanPages, &graph.Orphans)
+	}
+	return graph, nil
+}
+
+// completedSiteCrawl finds a crawl owned by the user and makes sure it is a completed site crawl.
+func (s *crawlService) completedSiteCrawl(ctx context.Context, crawlID, userID uint) (*entity.Crawl, error) {
+	crawl, err := s.crawlRepo.FindByID(ctx, crawlID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var opts entity.CrawlOptions
+	if len(crawl.Options) > 0 {
+		_ = json.Unmarshal(crawl.Options, &opts)
+	}
+	if opts.Mode != crawler.ModeSite {
+		return nil, ErrNotSiteCrawl
+	}
+	if crawl.Status != "COMPLETED" {
+		return nil, ErrCrawlNotCompleted
+	}
+	return crawl, nil
+}
+
+// StartCrawl queues a crawl of targetURL, as interactive unless opts say otherwise. With a positive
+// reuseWithin, an anonymous crawl instead copies the result of an identical crawl completed within
+// that time.
+func (s *crawlService) StartCrawl(ctx context.Context, userID uint, targetURL string, opts entity.CrawlOptions, creds *entity.CrawlCredentials, reuseWithin time.Duration) (*entity.Crawl, error) {
+	optionsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	crawlerOpts := s.crawlerOptions(&entity.Crawl{Options: optionsJSON})
+	if err := s.checkProxy(crawlerOpts, creds); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, userID, crawlerOpts); err != nil {
+		return nil, err
+	}
+	if reuseWithin > 0 && creds == nil {
+		crawl, err := s.reuseCrawl(ctx, userID, targetURL, opts, reuseWithin)
+		if err != nil || crawl != nil {
+			return crawl, err
+		}
+	}
+	return s.startCrawl(ctx, userID, targetURL, nil, opts, creds)
+}
+
+// reuseCrawl creates a completed crawl holding a copy of the result of the latest identical crawl
+// completed within reuseWithin. It returns nil without an error when there is none.
+func (s *crawlService) reuseCrawl(ctx context.Context, userID uint, targetURL string, opts entity.CrawlOptions, reuseWithin time.Duration) (*entity.Crawl, error) {
+	optionsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
 	crawl := &entity.Crawl{
-		UserID: userID,
-		URL:    targetURL,
-		Status: "PENDING",
+		UserID:   userID,
+		URL:      targetURL,
+		Status:   "COMPLETED",
+		Priority: crawlPriority(opts, PriorityInteractive),
+		Options:  optionsJSON,
+	}
+	crawl.ResultKey = s.resultKey(targetURL, s.crawlerOptions(crawl))
+	if crawl.ResultKey == "" {
+		return nil, nil
+	}
+	source, err := s.crawlRepo.FindLatestCompletedByResultKey(ctx, crawl.ResultKey, time.Now().Add(-reuseWithin))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.linkRepo.FindAllByCrawlID(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	pages, edges, err := s.pageRepo.FindGraph(ctx, source.ID)
+	if err != nil {
+		return nil, err
 	}
 
+	copyCrawlResult(crawl, source)
+	crawl.ReusedFromID = &source.ID
+	if err := s.reserveCrawl(ctx, userID); err != nil {
+		return nil, err
+	}
 	if err := s.crawlRepo.Create(ctx, crawl); err != nil {
-		log.Printf("Error creating initial crawl record: %v", err)
+		log.Printf("Error creating reused crawl record: %v", err)
+		s.recordUsage(ctx, userID, -1, 0)
+		return nil, err
+	}
+	for i := range links {
+		links[i].ID = 0
+	}
+	for i := range pages {
+		pages[i].ID = 0
+	}
+	for i := range edges {
+		edges[i].ID = 0
+	}
+	if err := s.linkRepo.ReplaceForCrawl(ctx, crawl.ID, links); err != nil {
+		log.Printf("Error copying links for crawl ID %d: %v", crawl.ID, err)
+	}
+	if err := s.pageRepo.ReplaceForCrawl(ctx, crawl.ID, pages, edges); err != nil {
+		log.Printf("Error copying pages for crawl ID %d: %v", crawl.ID, err)
+	}
+	log.Printf("Crawl %d reused the result of crawl %d for URL: %s", crawl.ID, source.ID, targetURL)
+	s.notify(userID, EventCrawlCreated, CrawlPayload{Crawl: crawl})
+	return crawl, nil
+}
+
+// copyCrawlResult copies the result of a completed crawl, the effective settings included.
+func copyCrawlResult(dst, src *entity.Crawl) {
+	dst.Settings = src.Settings
+	dst.ContentType = src.ContentType
+	dst.ResourceInfo = src.ResourceInfo
+	dst.HTMLVersion = src.HTMLVersion
+	dst.DocumentMode = src.DocumentMode
+	dst.Doctype = src.Doctype
+	dst.Encoding = src.Encoding
+	dst.EncodingSource = src.EncodingSource
+	dst.EncodingWarning = src.EncodingWarning
+	dst.Title = src.Title
+	dst.HeadingCounts = src.HeadingCounts
+	dst.InternalLinks = src.InternalLinks
+	dst.ExternalLinks = src.ExternalLinks
+	dst.BrokenLinks = src.BrokenLinks
+	dst.BrokenLinkDetail = src.BrokenLinkDetail
+	dst.BlockedLinks = src.BlockedLinks
+	dst.TotalLinks = src.TotalLinks
+	dst.PagesCrawled = src.PagesCrawled
+	dst.HasLoginForm = src.HasLoginForm
+	dst.Warnings = src.Warnings
+	dst.SitemapReport = src.SitemapReport
+	dst.OrphanPages = src.OrphanPages
+	dst.ProcessingTimeMs = src.ProcessingTimeMs
+}
+
+// checkProxy makes sure the proxies a crawl with opts and creds asks for are available.
+func (s *crawlService) checkProxy(opts crawler.Options, creds *entity.CrawlCredentials) error {
+	if creds != nil {
+		if err := credentialsOptions(creds, &opts); err != nil {
+			return err
+		}
+	}
+	return s.crawler.CheckProxy(opts)
+}
+
+// crawlPriority returns the priority chosen in opts, or fallback when none was.
+func crawlPriority(opts entity.CrawlOptions, fallback string) string {
+	if opts.Priority != "" {
+		return opts.Priority
+	}
+	return fallback
+}
+
+// startCrawl creates the record of a crawl, optionally as part of a batch, and queues it.
+func (s *crawlService) startCrawl(ctx context.Context, userID uint, targetURL string, batchID *uint, opts entity.CrawlOptions, creds *entity.CrawlCredentials) (*entity.Crawl, error) {
+	optionsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	crawl := &entity.Crawl{
+		UserID:   userID,
+		BatchID:  batchID,
+		URL:      targetURL,
+		Status:   "PENDING",
+		Priority: crawlPriority(opts, PriorityInteractive),
+		Options:  optionsJSON,
+	}
+
+	if creds != nil {
+		if s.credsKey == "" {
+			return nil, ErrCredentialsUnavailable
+		}
+		credsJSON, err := json.Marshal(creds)
+		if err != nil {
+			return nil, err
+		}
+		if crawl.Credentials, err = utils.EncryptString(s.credsKey, string(credsJSON)); err != nil {
+			return nil, err
+		}
+		crawl.HasCredentials = true
+	}
+
+	if err := s.reserveCrawl(ctx, userID); err != nil {
 		return nil, err
 	}
+	created, err := s.crawlRepo.CreateWithinActiveLimit(ctx, crawl, s.quota.MaxConcurrentCrawls)
+	if err != nil {
+		log.Printf("Error creating initial crawl record: %v", err)
+	}
+	if !created {
+		s.recordUsage(ctx, userID, -1, 0)
+		return nil, cmp.Or(err, s.concurrencyError())
+	}
+	s.notify(userID, EventCrawlCreated, CrawlPayload{Crawl: crawl})
 
-	go s.performCrawl(crawl)
+	s.queue.push(crawl)
 
 	return crawl, nil
 }
 
+// StartSitemapCrawls starts a crawl with the given options for every URL listed in a sitemap,
+// up to maxSitemapCrawls. It also returns the number of URLs the sitemap lists.
+func (s *crawlService) StartSitemapCrawls(ctx context.Context, userID uint, sitemapURL string, opts entity.CrawlOptions, creds *entity.CrawlCredentials) ([]*entity.Crawl, int, error) {
+	if creds != nil && s.credsKey == "" {
+		return nil, 0, ErrCredentialsUnavailable
+	}
+	opts.Priority = crawlPriority(opts, PriorityBulk)
+	optionsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The sitemap is fetched the way its URLs will be crawled.
+	crawlerOpts := s.crawlerOptions(&entity.Crawl{Options: optionsJSON})
+	if err := s.checkProxy(crawlerOpts, creds); err != nil {
+		return nil, 0, err
+	}
+	if err := s.checkQuota(ctx, userID, crawlerOpts); err != nil {
+		return nil, 0, err
+	}
+	if creds != nil {
+		if err := credentialsOptions(creds, &crawlerOpts); err != nil {
+			return nil, 0, err
+		}
+	}
+	urls, total, err := s.crawler.SitemapURLs(ctx, sitemapURL, crawlerOpts, maxSitemapCrawls)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	crawls := make([]*entity.Crawl, 0, len(urls))
+	for _, targetURL := range urls {
+		// Each crawl takes one of the user's concurrent slots and counts against the daily quotas.
+		if err := s.checkQuota(ctx, userID, crawlerOpts); err != nil {
+			return crawls, total, err
+		}
+		crawl, err := s.startCrawl(ctx, userID, targetURL, nil, opts, creds)
+		if err != nil {
+			return crawls, total, err
+		}
+		crawls = append(crawls, crawl)
+	}
+	return crawls, total, nil
+}
+
+// StartBatch validates and deduplicates the submitted rows of a batch, saves the batch with the
+// outcome of every row and starts a crawl with the given options for each accepted one.
+func (s *crawlService) StartBatch(ctx context.Context, userID uint, batch *entity.CrawlBatch, rows []entity.BatchRow, opts entity.CrawlOptions, creds *entity.CrawlCredentials) error {
+	if len(rows) > maxBatchRows {
+		return ErrBatchTooLarge
+	}
+	if creds != nil && s.credsKey == "" {
+		return ErrCredentialsUnavailable
+	}
+	opts.Priority = crawlPriority(opts, PriorityBulk)
+	optionsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	// Duplicates are detected the way the crawls will normalise their URLs.
+	crawlerOpts := s.crawlerOptions(&entity.Crawl{Options: optionsJSON})
+	normalizer := crawlerOpts.Normalizer
+	if err := s.checkProxy(crawlerOpts, creds); err != nil {
+		return err
+	}
+	if err := s.checkQuota(ctx, userID, crawlerOpts); err != nil {
+		return err
+	}
+
+	batch.UserID = userID
+	batch.Rows = len(rows)
+	if err := s.batchRepo.Create(ctx, batch); err != nil {
+		log.Printf("Error creating batch record: %v", err)
+		return err
+	}
+
+	seen := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		row.Status = entity.BatchRowRejected
+		targetURL, reason := validateBatchURL(row.URL)
+		if reason != "" {
+			row.Reason = reason
+			continue
+		}
+		normalized, err := normalizer.Normalize(targetURL)
+		if err != nil {
+			normalized = targetURL
+		}
+		if first, ok := seen[normalized]; ok {
+			row.Reason = fmt.Sprintf("duplicate of row %d", first)
+			continue
+		}
+		seen[normalized] = row.Row
+
+		// Each crawl takes one of the user's concurrent slots and counts against the daily quotas.
+		if err := s.checkQuota(ctx, userID, crawlerOpts); err != nil {
+			row.Reason = err.Error()
+			continue
+		}
+		crawl, err := s.startCrawl(ctx, userID, targetURL, &batch.ID, opts, creds)
+		if errors.Is(err, ErrQuotaExceeded) {
+			row.Reason = err.Error()
+			continue
+		}
+		if err != nil {
+			row.Reason = "failed to start crawl"
+			continue
+		}
+		row.Status = entity.BatchRowAccepted
+		row.CrawlID = crawl.ID
+	}
+
+	for _, row := range rows {
+		if row.Status == entity.BatchRowAccepted {
+			batch.Accepted++
+		} else {
+			batch.Rejected++
+		}
+	}
+	batch.Results, _ = json.Marshal(rows)
+	if err := s.batchRepo.Update(ctx, batch); err != nil {
+		log.Printf("Error saving results of batch ID %d: %v", batch.ID, err)
+		return err
+	}
+	// Crawls may all have finished while the rows were still being submitted, or none was accepted.
+	s.completeBatchIfDone(ctx, userID, batch.ID)
+	return nil
+}
+
+// completeBatchIfDone marks a submitted batch as completed once none of its crawls is pending or
+// processing any more, and tells its owner.
+func (s *crawlService) completeBatchIfDone(ctx context.Context, userID, batchID uint) {
+	batch, counts, err := s.GetBatch(ctx, batchID, userID)
+	if err != nil {
+		log.Printf("Error checking completion of batch ID %d: %v", batchID, err)
+		return
+	}
+	// Rows are saved once all of them were submitted; until then more crawls may still come.
+	if len(batch.Results) == 0 || batch.CompletedAt != nil || counts["PENDING"] > 0 || counts["PROCESSING"] > 0 {
+		return
+	}
+	completed, err := s.batchRepo.MarkCompleted(ctx, batchID, time.Now())
+	if err != nil {
+		log.Printf("Error marking batch ID %d as completed: %v", batchID, err)
+		return
+	}
+	if !completed {
+		return
+	}
+	s.notify(userID, EventBatchCompleted, BatchCompletedPayload{
+		BatchID:  batch.ID,
+		Rows:     batch.Rows,
+		Accepted: batch.Accepted,
+		Rejected: batch.Rejected,
+		Statuses: counts,
+	})
+}
+
+// validateBatchURL checks a submitted URL of a batch, returning it trimmed or the reason it is rejected.
+func validateBatchURL(raw string) (string, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", "empty URL"
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "", "invalid URL"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "unsupported scheme, only http and https can be crawled"
+	}
+	return raw, ""
+}
+
+// GetBatch returns a batch owned by the user together with the number of its crawls per status.
+func (s *crawlService) GetBatch(ctx context.Context, batchID, userID uint) (*entity.CrawlBatch, map[string]int64, error) {
+	batch, err := s.batchRepo.FindByID(ctx, batchID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	counts, err := s.crawlRepo.CountByStatusForBatch(ctx, batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batch, counts, nil
+}
+
 // performCrawl is the background worker that executes the crawl.
 // We make a small change to ensure it notifies clients when it starts processing.
 func (s *crawlService) performCrawl(crawlRecord *entity.Crawl) {
-	ctx := context.Background()
+	// Deleting the crawl cancels ctx.
+	ctx, done := s.startRunning(crawlRecord.ID)
+	defer done()
+
+	links := s.newLinkReservation(ctx, crawlRecord.UserID)
+	// A panic, e.g. in parsing a hostile page, fails this crawl but leaves the worker running.
+	defer func() {
+		if r := recover(); r != nil {
+			s.crawlPanicked(ctx, crawlRecord, links, r)
+		}
+	}()
 
 	// Update status to PROCESSING and save immediately.
+	previousStatus := crawlRecord.Status
 	crawlRecord.Status = "PROCESSING"
 	// Notify clients about the status change to PROCESSING
-	s.notifyStatusUpdate(crawlRecord)
+	s.notifyStatusChange(crawlRecord, previousStatus)
 	if err := s.crawlRepo.Update(ctx, crawlRecord); err != nil {
 		log.Printf("Error updating crawl status to PROCESSING for ID %d: %v", crawlRecord.ID, err)
 	}
 
-	log.Printf("Starting crawl for URL: %s (ID: %d)", crawlRecord.URL, crawlRecord.ID)
-	pageInfo, err := s.crawler.CrawlPage(crawlRecord.URL)
+	opts := s.crawlerOptions(crawlRecord)
+	err := s.applyCredentials(crawlRecord, &opts)
+
+	// Record the effective settings before running so failed crawls can be reproduced too.
+	settingsJSON, _ := json.Marshal(s.crawler.EffectiveSettings(opts))
+	crawlRecord.Settings = settingsJSON
+
+	var pageInfo *crawler.PageInfo
+	if err == nil {
+		log.Printf("Starting crawl for URL: %s (ID: %d)", crawlRecord.URL, crawlRecord.ID)
+		// Identical crawls running at the same time share a single execution.
+		crawlRecord.ResultKey = s.resultKey(crawlRecord.URL, opts)
+		opts.LinkBudget = links.grant
+		var shared bool
+		pageInfo, shared, err = s.flights.do(ctx, crawlRecord.ResultKey, s.progressReporter(crawlRecord), func(progress crawler.ProgressFunc) (*crawler.PageInfo, bool, error) {
+			opts := opts
+			opts.Progress = progress
+			info, err := s.crawler.CrawlPage(ctx, crawlRecord.URL, opts)
+			return info, ctx.Err() == nil && !links.cutShort(), err
+		})
+		// A shared result still counts its link checks against this crawl's owner.
+		if shared && pageInfo != nil && links.grant(pageInfo.Requests) < pageInfo.Requests {
+			log.Printf("Crawl %d can't afford the result of an identical crawl and runs on its own", crawlRecord.ID)
+			links.release(nil)
+			links = s.newLinkReservation(ctx, crawlRecord.UserID)
+			opts.LinkBudget = links.grant
+			opts.Progress = s.progressReporter(crawlRecord)
+			pageInfo, err = s.crawler.CrawlPage(ctx, crawlRecord.URL, opts)
+		} else if shared {
+			log.Printf("Crawl %d shared the result of an identical crawl in progress", crawlRecord.ID)
+		}
+	}
+
+	if ctx.Err() != nil {
+		log.Printf("Crawl %d was deleted while running", crawlRecord.ID)
+		links.release(pageInfo)
+		if crawlRecord.BatchID != nil {
+			s.completeBatchIfDone(context.Background(), crawlRecord.UserID, *crawlRecord.BatchID)
+		}
+		return
+	}
 
 	// Now, populate the final results into the crawlRecord struct.
-	if err != nil {
+	timedOut := errors.Is(err, crawler.ErrTimedOut)
+	if errors.Is(err, crawler.ErrBlockedDestination) {
+		log.Printf("Crawl blocked by network policy for URL %s: %v", crawlRecord.URL, err)
+		crawlRecord.Status = "BLOCKED"
+		crawlRecord.ErrorMessage = err.Error()
+	} else if timedOut {
+		log.Printf("Crawl timed out for URL %s: %v", crawlRecord.URL, err)
+		crawlRecord.Status = "TIMED_OUT"
+		crawlRecord.ErrorMessage = err.Error()
+	} else if err != nil {
 		log.Printf("Crawl failed for URL %s: %v", crawlRecord.URL, err)
 		crawlRecord.Status = "FAILED"
 		crawlRecord.ErrorMessage = err.Error()
 	} else {
 		log.Printf("Crawl completed for URL: %s", crawlRecord.URL)
 		crawlRecord.Status = "COMPLETED"
+	}
+
+	// Crawls that timed out keep what they got done before their deadline.
+	if pageInfo != nil && (err == nil || timedOut) {
+		crawlRecord.ContentType = utils.Truncate(pageInfo.ContentType, entity.MaxContentTypeLength)
 		crawlRecord.HTMLVersion = pageInfo.HTMLVersion
+		crawlRecord.DocumentMode = pageInfo.DocumentMode
+		crawlRecord.Doctype = utils.Truncate(pageInfo.Doctype, entity.MaxDoctypeLength)
+		crawlRecord.Encoding = utils.Truncate(pageInfo.Encoding.Name, entity.MaxEncodingLength)
+		crawlRecord.EncodingSource = pageInfo.Encoding.Source
+		crawlRecord.EncodingWarning = pageInfo.Encoding.Warning
 		crawlRecord.Title = pageInfo.Title
 		crawlRecord.InternalLinks = pageInfo.InternalLinks
 		crawlRecord.ExternalLinks = pageInfo.ExternalLinks
 		crawlRecord.BrokenLinks = pageInfo.BrokenLinks
+		crawlRecord.BlockedLinks = pageInfo.BlockedLinks
 		crawlRecord.TotalLinks = pageInfo.TotalLinks
+		crawlRecord.PagesCrawled = len(pageInfo.Pages)
 		crawlRecord.HasLoginForm = pageInfo.HasLoginForm
 		crawlRecord.ProcessingTimeMs = pageInfo.ProcessingTime.Milliseconds()
 
+		if pageInfo.Resource != nil {
+			resourceJSON, _ := json.Marshal(pageInfo.Resource)
+			crawlRecord.ResourceInfo = resourceJSON
+		}
+		if len(pageInfo.Warnings) > 0 {
+			warnings := make([]entity.CrawlWarning, len(pageInfo.Warnings))
+			for i, w := range pageInfo.Warnings {
+				warnings[i] = entity.CrawlWarning{Code: w.Code, Message: w.Message}
+			}
+			warningsJSON, _ := json.Marshal(warnings)
+			crawlRecord.Warnings = warningsJSON
+		}
+		if pageInfo.Sitemap != nil {
+			sitemapJSON, _ := json.Marshal(pageInfo.Sitemap)
+			crawlRecord.SitemapReport = sitemapJSON
+		}
+		if pageInfo.Graph != nil {
+			orphansJSON, _ := json.Marshal(pageInfo.Graph.Orphans)
+			crawlRecord.OrphanPages = orphansJSON
+		}
 		headingsJSON, _ := json.Marshal(pageInfo.HeadingCounts)
 		crawlRecord.HeadingCounts = headingsJSON
 		brokenLinksJSON, _ := json.Marshal(pageInfo.BrokenLinkDetail)
 		crawlRecord.BrokenLinkDetail = brokenLinksJSON
 	}
 
-	// Notify clients about the final status (COMPLETED or FAILED)
-	s.notifyStatusUpdate(crawlRecord)
+	// Give back the link checks the crawl reserved but didn't get to make.
+	links.release(pageInfo)
+
 	// Save the final, updated record to the database.
 	if err := s.crawlRepo.Update(ctx, crawlRecord); err != nil {
 		log.Printf("Error saving final crawl result for ID %d: %v", crawlRecord.ID, err)
+		// Don't leave the crawl PROCESSING forever: at least record that it failed.
+		crawlRecord.Status = "FAILED"
+		crawlRecord.ErrorMessage = "The crawl finished but its result could not be saved"
+		if err := s.crawlRepo.UpdateStatus(ctx, crawlRecord.ID, crawlRecord.Status, crawlRecord.ErrorMessage); err != nil {
+			log.Printf("Error marking crawl ID %d as failed: %v", crawlRecord.ID, err)
+		}
 	} else {
 		log.Printf("Crawl %d finished and saved with status: %s", crawlRecord.ID, crawlRecord.Status)
 	}
+	// Notify clients about the final status (COMPLETED, FAILED, BLOCKED or TIMED_OUT)
+	s.notifyStatusChange(crawlRecord, "PROCESSING")
+
+	// Persist every checked link so the UI can list all of them, not just the broken ones.
+	if pageInfo != nil {
+		if err := s.linkRepo.ReplaceForCrawl(ctx, crawlRecord.ID, toCrawlLinks(pageInfo.Links)); err != nil {
+			log.Printf("Error saving links for crawl ID %d: %v", crawlRecord.ID, err)
+		}
+		var edges []entity.CrawlEdge
+		if pageInfo.Graph != nil {
+			edges = toCrawlEdges(pageInfo.Graph.Edges)
+		}
+		if err := s.pageRepo.ReplaceForCrawl(ctx, crawlRecord.ID, toCrawlPages(pageInfo.Pages), edges); err != nil {
+			log.Printf("Error saving pages for crawl ID %d: %v", crawlRecord.ID, err)
+		}
+	}
+
+	if crawlRecord.BatchID != nil {
+		s.completeBatchIfDone(ctx, crawlRecord.UserID, *crawlRecord.BatchID)
+	}
+}
+
+// crawlPanicked records a crawl that panicked as failed, instead of leaving it PROCESSING, and
+// gives back the link checks it reserved.
+func (s *crawlService) crawlPanicked(ctx context.Context, crawlRecord *entity.Crawl, links *linkReservation, r any) {
+	log.Printf("Crawl %d panicked: %v\n%s", crawlRecord.ID, r, debug.Stack())
+	links.release(nil)
+	if ctx.Err() == nil {
+		crawlRecord.Status = "FAILED"
+		crawlRecord.ErrorMessage = "The crawl failed unexpectedly"
+		if err := s.crawlRepo.UpdateStatus(ctx, crawlRecord.ID, crawlRecord.Status, crawlRecord.ErrorMessage); err != nil {
+			log.Printf("Error marking crawl ID %d as failed: %v", crawlRecord.ID, err)
+		}
+		s.notifyStatusChange(crawlRecord, "PROCESSING")
+	}
+	if crawlRecord.BatchID != nil {
+		s.completeBatchIfDone(context.Background(), crawlRecord.UserID, *crawlRecord.BatchID)
+	}
+}
+
+// startRunning registers a crawl being performed and returns the context it runs with, which
+// cancelRunning cancels, and the function to call once it is done.
+func (s *crawlService) startRunning(crawlID uint) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.runningMu.Lock()
+	s.running[crawlID] = cancel
+	s.runningMu.Unlock()
+	return ctx, func() {
+		s.runningMu.Lock()
+		delete(s.running, crawlID)
+		s.runningMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelRunning cancels those of the crawls that are being performed.
+func (s *crawlService) cancelRunning(crawlIDs ...uint) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	for _, id := range crawlIDs {
+		if cancel, ok := s.running[id]; ok {
+			cancel()
+		}
+	}
+}
+
+// crawlerOptions builds the crawler options from the settings stored on the crawl record.
+// Records created before options existed, or with unset fields, fall back to the defaults.
+func (s *crawlService) crawlerOptions(crawlRecord *entity.Crawl) crawler.Options {
+	opts := s.crawler.DefaultOptions()
+
+	var stored entity.CrawlOptions
+	if len(crawlRecord.Options) > 0 {
+		if err := json.Unmarshal(crawlRecord.Options, &stored); err != nil {
+			log.Printf("Ignoring invalid options for crawl ID %d: %v", crawlRecord.ID, err)
+		}
+	}
+
+	if stored.Scope != "" {
+		opts.Scope.Mode = crawler.ScopeMode(stored.Scope)
+	}
+	opts.Scope.Allowlist = stored.AllowedHosts
+	if stored.TrailingSlash != "" {
+		opts.Normalizer.TrailingSlash = crawler.TrailingSlashPolicy(stored.TrailingSlash)
+	}
+	if preset, ok := crawler.UserAgentPresets[stored.UserAgentPreset]; ok {
+		opts.UserAgent = preset
+	}
+	if stored.UserAgent != "" {
+		opts.UserAgent = stored.UserAgent
+	}
+	if stored.RequestTimeoutMs > 0 {
+		opts.RequestTimeout = time.Duration(stored.RequestTimeoutMs) * time.Millisecond
+	}
+	if stored.PageTimeoutMs > 0 {
+		opts.PageTimeout = time.Duration(stored.PageTimeoutMs) * time.Millisecond
+	}
+	if stored.TimeoutSeconds > 0 {
+		opts.Timeout = time.Duration(stored.TimeoutSeconds) * time.Second
+	}
+	if stored.DelayMs > 0 {
+		opts.Delay = time.Duration(stored.DelayMs) * time.Millisecond
+	}
+	if stored.Proxy != "" {
+		opts.Proxy = crawler.ProxyMode(stored.Proxy)
+	}
+	opts.CheckSitemaps = stored.CheckSitemaps
+	if stored.Mode != "" {
+		opts.Mode = stored.Mode
+	}
+	if stored.MaxPages > 0 {
+		opts.MaxPages = stored.MaxPages
+	} else if s.quota.MaxPagesPerCrawl > 0 {
+		opts.MaxPages = min(opts.MaxPages, s.quota.MaxPagesPerCrawl)
+	}
+	if stored.MaxDepth > 0 {
+		opts.MaxDepth = stored.MaxDepth
+	}
+	if r := stored.Retry; r != nil {
+		if r.MaxAttempts > 0 {
+			opts.Retry.MaxAttempts = r.MaxAttempts
+		}
+		if r.InitialBackoffMs > 0 {
+			opts.Retry.InitialBackoff = time.Duration(r.InitialBackoffMs) * time.Millisecond
+		}
+		if r.MaxBackoffMs > 0 {
+			opts.Retry.MaxBackoff = time.Duration(r.MaxBackoffMs) * time.Millisecond
+		}
+		if len(r.StatusCodes) > 0 {
+			opts.Retry.RetryableStatusCodes = r.StatusCodes
+		}
+		if len(r.ErrorClasses) > 0 {
+			opts.Retry.RetryableErrors = r.ErrorClasses
+		}
+	}
+	return opts
 }
 
-// Helper function to send notifications
-func (s *crawlService) notifyStatusUpdate(crawlRecord *entity.Crawl) {
-	updateMsg, err := json.Marshal(crawlRecord)
+// applyCredentials decrypts the credentials stored on the crawl record, if any,
+// and adds the target credentials and dedicated proxy to opts.
+func (s *crawlService) applyCredentials(crawlRecord *entity.Crawl, opts *crawler.Options) error {
+	if !crawlRecord.HasCredentials {
+		return nil
+	}
+	plaintext, err := utils.DecryptString(s.credsKey, crawlRecord.Credentials)
 	if err != nil {
-		log.Printf("Error marshalling crawl update for notification: %v", err)
-		return
+		return fmt.Errorf("failed to decrypt crawl credentials: %w", err)
+	}
+	var creds entity.CrawlCredentials
+	if err := json.Unmarshal([]byte(plaintext), &creds); err != nil {
+		return fmt.Errorf("failed to decode crawl credentials: %w", err)
+	}
+	return credentialsOptions(&creds, opts)
+}
+
+// credentialsOptions adds the target credentials and dedicated proxy of creds to opts.
+func credentialsOptions(creds *entity.CrawlCredentials, opts *crawler.Options) error {
+	if creds.ProxyURL != "" {
+		proxyURL, err := crawler.ParseProxyURL(creds.ProxyURL)
+		if err != nil {
+			return err
+		}
+		opts.ProxyURL = proxyURL
 	}
-	s.notifier.Notify(crawlRecord.UserID, updateMsg)
+	if len(creds.Headers) == 0 && len(creds.Cookies) == 0 && creds.Username == "" && creds.Password == "" && creds.BearerToken == "" {
+		return nil
+	}
+	auth := &crawler.Auth{
+		Headers:      creds.Headers,
+		Username:     creds.Username,
+		Password:     creds.Password,
+		BearerToken:  creds.BearerToken,
+		ApplyToLinks: creds.ApplyToLinks,
+	}
+	for _, cookie := range creds.Cookies {
+		auth.Cookies = append(auth.Cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	opts.Auth = auth
+	return nil
+}
+
+// toCrawlLinks maps the crawler's link results to storable entities.
+func toCrawlLinks(results []crawler.LinkResult) []entity.CrawlLink {
+	links := make([]entity.CrawlLink, 0, len(results))
+	for _, r := range results {
+		linkType := "external"
+		if r.IsInternal {
+			linkType = "internal"
+		}
+		links = append(links, entity.CrawlLink{
+			URL:            r.URL,
+			NormalizedURL:  r.NormalizedURL,
+			LinkType:       linkType,
+			StatusCode:     r.StatusCode,
+			CheckError:     r.Error,
+			ResponseTimeMs: r.ResponseTime.Milliseconds(),
+			Attempts:       r.Attempts,
+			Blocked:        r.Blocked,
+			RedirectTarget: r.RedirectURL,
+			AnchorText:     r.AnchorText,
+		})
+	}
+	return links
+}
+
+// toCrawlPages maps the pages of a site crawl to storable entities.
+func toCrawlPages(pages []crawler.SitePage) []entity.CrawlPage {
+	result := make([]entity.CrawlPage, 0, len(pages))
+	for _, p := range pages {
+		result = append(result, entity.CrawlPage{
+			URL:           p.URL,
+			NormalizedURL: p.NormalizedURL,
+			Depth:         p.Depth,
+			StatusCode:    p.StatusCode,
+			RedirectURL:   p.RedirectURL,
+			ContentType:   utils.Truncate(p.ContentType, entity.MaxContentTypeLength),
+			Title:         p.Title,
+			LastModified:  p.LastModified,
+			Noindex:       p.Noindex,
+			CanonicalURL:  p.CanonicalURL,
+			Indexable:     p.Indexable,
+			FetchError:    p.Error,
+			Truncated:     p.Truncated,
+			Inlinks:       p.Inlinks,
+			Outlinks:      p.Outlinks,
+			PageRank:      p.PageRank,
+			DeadEnd:       p.DeadEnd,
+		})
+	}
+	return result
+}
+
+// toCrawlEdges maps the link graph edges of a site crawl to storable entities.
+func toCrawlEdges(edges []crawler.SiteEdge) []entity.CrawlEdge {
+	result := make([]entity.CrawlEdge, 0, len(edges))
+	for _, e := range edges {
+		result = append(result, entity.CrawlEdge{
+			SourceURL:  e.Source,
+			TargetURL:  e.Target,
+			AnchorText: e.AnchorText,
+			Redirect:   e.Redirect,
+		})
+	}
+	return result
 }
 
 // RerunCrawl now finds, resets, and updates an existing crawl record.
@@ -124,32 +927,74 @@ func (s *crawlService) RerunCrawl(ctx context.Context, crawlID, userID uint) (*e
 	if err != nil {
 		return nil, err // Fails if not found or not owned by user
 	}
+	if crawlToRerun.HasCredentials && s.credsKey == "" {
+		return nil, ErrCredentialsUnavailable
+	}
+	crawlerOpts := s.crawlerOptions(crawlToRerun)
+	if err := s.applyCredentials(crawlToRerun, &crawlerOpts); err != nil {
+		return nil, err
+	}
+	if err := s.crawler.CheckProxy(crawlerOpts); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, userID, crawlerOpts); err != nil {
+		return nil, err
+	}
 
 	// 2. Reset the fields of the existing crawl record.
+	previousStatus := crawlToRerun.Status
 	crawlToRerun.Status = "PENDING"
+	crawlToRerun.ContentType = ""
+	crawlToRerun.ResourceInfo = nil
 	crawlToRerun.HTMLVersion = ""
+	crawlToRerun.DocumentMode = ""
+	crawlToRerun.Doctype = ""
+	crawlToRerun.Encoding = ""
+	crawlToRerun.EncodingSource = ""
+	crawlToRerun.EncodingWarning = ""
 	crawlToRerun.Title = ""
+	crawlToRerun.Warnings = nil
+	crawlToRerun.SitemapReport = nil
+	crawlToRerun.OrphanPages = nil
 	crawlToRerun.HeadingCounts = nil
 	crawlToRerun.InternalLinks = 0
 	crawlToRerun.ExternalLinks = 0
 	crawlToRerun.BrokenLinks = 0
+	crawlToRerun.BlockedLinks = 0
 	crawlToRerun.TotalLinks = 0
+	crawlToRerun.PagesCrawled = 0
 	crawlToRerun.BrokenLinkDetail = nil
 	crawlToRerun.HasLoginForm = false
 	crawlToRerun.ProcessingTimeMs = 0
 	crawlToRerun.ErrorMessage = ""
+	crawlToRerun.Settings = nil
+	crawlToRerun.ResultKey = ""
+	crawlToRerun.ReusedFromID = nil
 
-	// 3. Save these reset fields to the database immediately.
-	if err := s.crawlRepo.Update(ctx, crawlToRerun); err != nil {
-		log.Printf("Error resetting crawl record for re-run (ID %d): %v", crawlToRerun.ID, err)
+	// 3. Save these reset fields to the database immediately and drop the old links.
+	if err := s.reserveCrawl(ctx, userID); err != nil {
 		return nil, err
 	}
+	saved, err := s.crawlRepo.UpdateWithinActiveLimit(ctx, crawlToRerun, s.quota.MaxConcurrentCrawls)
+	if err != nil {
+		log.Printf("Error resetting crawl record for re-run (ID %d): %v", crawlToRerun.ID, err)
+	}
+	if !saved {
+		s.recordUsage(ctx, userID, -1, 0)
+		return nil, cmp.Or(err, s.concurrencyError())
+	}
+	if err := s.linkRepo.ReplaceForCrawl(ctx, crawlToRerun.ID, nil); err != nil {
+		log.Printf("Error clearing links for re-run (ID %d): %v", crawlToRerun.ID, err)
+	}
+	if err := s.pageRepo.ReplaceForCrawl(ctx, crawlToRerun.ID, nil, nil); err != nil {
+		log.Printf("Error clearing pages for re-run (ID %d): %v", crawlToRerun.ID, err)
+	}
 
 	// 4. Notify the client via WebSocket that the status is now PENDING.
-	s.notifyStatusUpdate(crawlToRerun)
+	s.notifyStatusChange(crawlToRerun, previousStatus)
 
 	// 5. Launch the background crawl job on the updated record.
-	go s.performCrawl(crawlToRerun)
+	s.queue.push(crawlToRerun)
 
 	// 6. Return the updated "PENDING" record to the user.
 	return crawlToRerun, nil
@@ -157,10 +1002,20 @@ func (s *crawlService) RerunCrawl(ctx context.Context, crawlID, userID uint) (*e
 
 // DeleteCrawl deletes a single crawl record.
 func (s *crawlService) DeleteCrawl(ctx context.Context, crawlID, userID uint) error {
-	return s.crawlRepo.Delete(ctx, crawlID, userID)
+	if err := s.crawlRepo.Delete(ctx, crawlID, userID); err != nil {
+		return err
+	}
+	s.cancelRunning(crawlID)
+	s.notify(userID, EventCrawlDeleted, CrawlsDeletedPayload{CrawlIDs: []uint{crawlID}})
+	return nil
 }
 
 // DeleteCrawlsBulk deletes multiple crawl records.
 func (s *crawlService) DeleteCrawlsBulk(ctx context.Context, crawlIDs []uint, userID uint) error {
-	return s.crawlRepo.DeleteBulk(ctx, crawlIDs, userID)
+	if err := s.crawlRepo.DeleteBulk(ctx, crawlIDs, userID); err != nil {
+		return err
+	}
+	s.cancelRunning(crawlIDs...)
+	s.notify(userID, EventCrawlDeleted, CrawlsDeletedPayload{CrawlIDs: crawlIDs})
+	return nil
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/google/uuid"
+)
+
+// EventVersion is the version of the event envelope and payloads. It changes whenever a payload
+// changes in a way that could break clients; new fields may be added without a new version.
+const EventVersion = 1
+
+// Types of the events sent to users over the websocket.
+const (
+	EventCrawlCreated       = "crawl.created"        // A crawl was submitted, or created from a reused result
+	EventCrawlStatusChanged = "crawl.status_changed" // A crawl moved to another status
+	EventCrawlProgress      = "crawl.progress"       // A running crawl made progress
+	EventCrawlDeleted       = "crawl.deleted"        // Crawls were deleted
+	EventBatchCompleted     = "batch.completed"      // Every crawl of a batch finished
+)
+
+// EventSchema is the JSON schema of the events, published by the API.
+//
+//go:embed events.schema.json
+var EventSchema []byte
+
+// Event is the envelope every event is sent in.
+type Event struct {
+	Type      string    `json:"type"`
+	Version   int       `json:"version"`
+	ID        string    `json:"id"` // Unique per event, to tell repeated deliveries apart
+	Timestamp time.Time `json:"timestamp"`
+	Payload   any       `json:"payload"`
+}
+
+// CrawlPayload is the payload of crawl.created events.
+type CrawlPayload struct {
+	Crawl *entity.Crawl `json:"crawl"`
+}
+
+// CrawlStatusPayload is the payload of crawl.status_changed events.
+type CrawlStatusPayload struct {
+	CrawlID        uint          `json:"crawl_id"`
+	Status         string        `json:"status"`
+	PreviousStatus string        `json:"previous_status"`
+	Crawl          *entity.Crawl `json:"crawl"` // The crawl as of the change
+}
+
+// CrawlProgressPayload is the payload of crawl.progress events.
+type CrawlProgressPayload struct {
+	CrawlID      uint   `json:"crawl_id"`
+	Phase        string `json:"phase"` // fetching, parsing, checking_links, checking_sitemaps, crawling_site
+	LinksChecked int    `json:"links_checked"`
+	LinksTotal   int    `json:"links_total"`
+	BrokenLinks  int    `json:"broken_links"`  // Among the links checked so far
+	PagesVisited int    `json:"pages_visited"` // Site crawls only
+	ElapsedMs    int64  `json:"elapsed_ms"`
+	EtaMs        *int64 `json:"eta_ms,omitempty"` // Estimated time left in the current phase, when it can be told
+}
+
+// CrawlsDeletedPayload is the payload of crawl.deleted events.
+type CrawlsDeletedPayload struct {
+	CrawlIDs []uint `json:"crawl_ids"`
+}
+
+// BatchCompletedPayload is the payload of batch.completed events.
+type BatchCompletedPayload struct {
+	BatchID  uint             `json:"batch_id"`
+	Rows     int              `json:"rows"`
+	Accepted int              `json:"accepted"`
+	Rejected int              `json:"rejected"`
+	Statuses map[string]int64 `json:"statuses"` // Crawls of the batch per final status
+}
+
+// notify sends an event of the given type to a user.
+func (s *crawlService) notify(userID uint, eventType string, payload any) {
+	message, err := json.Marshal(Event{
+		Type:      eventType,
+		Version:   EventVersion,
+		ID:        uuid.NewString(),
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+	})
+	if err != nil {
+		log.Printf("Error marshalling %s event for user ID %d: %v", eventType, userID, err)
+		return
+	}
+	s.notifier.Notify(userID, message)
+}
+
+// notifyStatusChange tells the owner of a crawl that it moved from previous to its current status.
+func (s *crawlService) notifyStatusChange(crawlRecord *entity.Crawl, previous string) {
+	s.notify(crawlRecord.UserID, EventCrawlStatusChanged, CrawlStatusPayload{
+		CrawlID:        crawlRecord.ID,
+		Status:         crawlRecord.Status,
+		PreviousStatus: previous,
+		Crawl:          crawlRecord,
+	})
+}
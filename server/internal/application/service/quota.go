@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
+)
+
+// ErrQuotaExceeded is returned when starting a crawl would exceed one of the user's quotas.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota holds the limits every user's crawling is subject to; zero disables a limit.
+type Quota struct {
+	MaxConcurrentCrawls int64 // Crawls pending or processing at the same time
+	DailyCrawls         int64 // Crawls started or re-run per UTC day
+	DailyLinkChecks     int64 // Links checked per UTC day
+	MaxPagesPerCrawl    int   // Pages a single site crawl may fetch
+}
+
+// Usage is what a user consumed of their quotas, and what remains.
+type Usage struct {
+	Quota            Quota
+	ConcurrentCrawls int64
+	DailyCrawls      int64
+	DailyLinkChecks  int64
+	ResetsAt         time.Time // Start of the next UTC day, when the daily counters reset
+}
+
+// Remaining returns how much of a limit is left, or -1 for unlimited.
+func Remaining(limit, used int64) int64 {
+	if limit <= 0 {
+		return -1
+	}
+	return max(limit-used, 0)
+}
+
+// usageDay returns the UTC day the daily quotas of t are counted on.
+func usageDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// GetUsage returns what the user consumed of their quotas today.
+func (s *crawlService) GetUsage(ctx context.Context, userID uint) (*Usage, error) {
+	day := usageDay(time.Now())
+	daily, err := s.usageRepo.FindByDay(ctx, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.crawlRepo.CountActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Usage{
+		Quota:            s.quota,
+		ConcurrentCrawls: active,
+		DailyCrawls:      daily.Crawls,
+		DailyLinkChecks:  daily.LinkChecks,
+		ResetsAt:         day.Add(24 * time.Hour),
+	}, nil
+}
+
+// checkQuota makes sure the user may start another crawl with opts. The concurrency limit and the
+// daily quotas are checked to fail early; they are enforced as each crawl is created and runs.
+func (s *crawlService) checkQuota(ctx context.Context, userID uint, opts crawler.Options) error {
+	if opts.Mode == crawler.ModeSite && s.quota.MaxPagesPerCrawl > 0 && opts.MaxPages > s.quota.MaxPagesPerCrawl {
+		return fmt.Errorf("%w: site crawls may fetch at most %d pages", ErrQuotaExceeded, s.quota.MaxPagesPerCrawl)
+	}
+	usage, err := s.GetUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.quota.MaxConcurrentCrawls > 0 && usage.ConcurrentCrawls >= s.quota.MaxConcurrentCrawls {
+		return s.concurrencyError()
+	}
+	if s.quota.DailyCrawls > 0 && usage.DailyCrawls >= s.quota.DailyCrawls {
+		return fmt.Errorf("%w: at most %d crawls may be started per day", ErrQuotaExceeded, s.quota.DailyCrawls)
+	}
+	if s.quota.DailyLinkChecks > 0 && usage.DailyLinkChecks >= s.quota.DailyLinkChecks {
+		return fmt.Errorf("%w: at most %d links may be checked per day", ErrQuotaExceeded, s.quota.DailyLinkChecks)
+	}
+	return nil
+}
+
+// concurrencyError is returned when the user has as many crawls pending or processing as they may.
+func (s *crawlService) concurrencyError() error {
+	return fmt.Errorf("%w: at most %d crawls may run at the same time", ErrQuotaExceeded, s.quota.MaxConcurrentCrawls)
+}
+
+// reserveCrawl counts a crawl against the user's daily crawls quota, failing if none is left.
+func (s *crawlService) reserveCrawl(ctx context.Context, userID uint) error {
+	ok, err := s.usageRepo.ReserveCrawl(ctx, userID, usageDay(time.Now()), s.quota.DailyCrawls)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: at most %d crawls may be started per day", ErrQuotaExceeded, s.quota.DailyCrawls)
+	}
+	return nil
+}
+
+// linkReservation takes the link checks of a crawl run by the user out of the daily quota before
+// they are made, and gives back those the crawl didn't get to make.
+type linkReservation struct {
+	s        *crawlService
+	ctx      context.Context
+	userID   uint
+	mu       sync.Mutex
+	reserved int64 // Checks taken out of the quota
+	short    bool  // Whether a request was granted fewer checks than it asked for
+	released bool
+}
+
+func (s *crawlService) newLinkReservation(ctx context.Context, userID uint) *linkReservation {
+	return &linkReservation{s: s, ctx: ctx, userID: userID}
+}
+
+// grant is the crawler.LinkBudgetFunc of the crawl.
+func (r *linkReservation) grant(n int) int {
+	if n < 0 {
+		r.giveBack(int64(-n))
+		return 0
+	}
+	if n == 0 {
+		return 0
+	}
+	granted, err := r.s.usageRepo.ReserveLinkChecks(r.ctx, r.userID, usageDay(time.Now()), int64(n), r.s.quota.DailyLinkChecks)
+	if err != nil {
+		// Don't fail the crawl over its accounting.
+		log.Printf("Error reserving link checks of user ID %d: %v", r.userID, err)
+		return n
+	}
+	r.mu.Lock()
+	r.reserved += granted
+	r.short = r.short || granted < int64(n)
+	r.mu.Unlock()
+	return int(granted)
+}
+
+// cutShort reports whether the crawl was granted fewer checks than it asked for.
+func (r *linkReservation) cutShort() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.short
+}
+
+// release gives back the checks reserved beyond the requests the crawl made, all of them when it
+// has no result. Only the first release gives anything back.
+func (r *linkReservation) release(info *crawler.PageInfo) {
+	var used int64
+	if info != nil {
+		used = int64(info.Requests)
+	}
+	r.mu.Lock()
+	unused := r.reserved - used
+	if r.released {
+		unused = 0
+	}
+	r.released = true
+	r.mu.Unlock()
+	r.giveBack(unused)
+}
+
+// giveBack returns n reserved checks to the quota.
+func (r *linkReservation) giveBack(n int64) {
+	if n <= 0 {
+		return
+	}
+	r.mu.Lock()
+	r.reserved -= n
+	r.mu.Unlock()
+	r.s.recordUsage(r.ctx, r.userID, 0, -n)
+}
+
+// recordUsage adds crawls and link checks to the user's usage of today.
+func (s *crawlService) recordUsage(ctx context.Context, userID uint, crawls, linkChecks int64) {
+	if err := s.usageRepo.Add(ctx, userID, usageDay(time.Now()), crawls, linkChecks); err != nil {
+		log.Printf("Error recording usage of user ID %d: %v", userID, err)
+	}
+}
@@ -11,21 +11,109 @@ type BrokenLinkDetail struct {
 	StatusCode int    `json:"status_code"`
 }
 
+// CrawlOptions is a helper struct for storing the per-crawl settings chosen by the user.
+type CrawlOptions struct {
+	Scope         string        `json:"scope,omitempty"`          // host, domain, allowlist
+	AllowedHosts  []string      `json:"allowed_hosts,omitempty"`  // Used by the allowlist scope
+	TrailingSlash string        `json:"trailing_slash,omitempty"` // strip, add, keep
+	Retry         *RetryOptions `json:"retry,omitempty"`          // Overrides the server's default retry policy
+
+	// Fetch overrides; zero values keep the server defaults.
+	UserAgentPreset  string `json:"user_agent_preset,omitempty"` // chrome, firefox, safari, mobile, googlebot
+	UserAgent        string `json:"user_agent,omitempty"`        // Custom user agent, takes precedence over the preset
+	RequestTimeoutMs int    `json:"request_timeout_ms,omitempty"`
+	PageTimeoutMs    int    `json:"page_timeout_ms,omitempty"`
+	TimeoutSeconds   int    `json:"timeout_seconds,omitempty"` // Deadline of the whole crawl
+	DelayMs          int    `json:"delay_ms,omitempty"`
+	Proxy            string `json:"proxy,omitempty"` // direct, pool
+
+	CheckSitemaps bool `json:"check_sitemaps,omitempty"` // Validate the target's sitemaps and check their URLs
+
+	Priority string `json:"priority,omitempty"` // interactive, bulk; empty picks the default of the submission
+
+	// Site crawls also follow the target's internal links; zero limits keep the server defaults.
+	Mode     string `json:"mode,omitempty"` // page, site
+	MaxPages int    `json:"max_pages,omitempty"`
+	MaxDepth int    `json:"max_depth,omitempty"`
+}
+
+// RetryOptions is a helper struct for storing per-crawl retry policy overrides.
+// Zero values keep the server default.
+type RetryOptions struct {
+	MaxAttempts      int      `json:"max_attempts,omitempty"`
+	InitialBackoffMs int      `json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     int      `json:"max_backoff_ms,omitempty"`
+	StatusCodes      []int    `json:"status_codes,omitempty"`
+	ErrorClasses     []string `json:"error_classes,omitempty"` // timeout, connection, dns
+}
+
+// CrawlCredentials is a helper struct for the credentials an authenticated crawl sends to the target.
+// It is only ever stored encrypted and never serialised into API responses.
+type CrawlCredentials struct {
+	Headers      map[string]string `json:"headers,omitempty"`
+	Cookies      []CrawlCookie     `json:"cookies,omitempty"`
+	Username     string            `json:"username,omitempty"` // HTTP basic auth
+	Password     string            `json:"password,omitempty"`
+	BearerToken  string            `json:"bearer_token,omitempty"`
+	ApplyToLinks bool              `json:"apply_to_links,omitempty"` // Also authenticate same-origin link checks
+	ProxyURL     string            `json:"proxy_url,omitempty"`      // Dedicated proxy, may embed proxy credentials
+}
+
+// CrawlCookie is a single cookie sent with an authenticated crawl.
+type CrawlCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CrawlWarning is a helper struct for a non-fatal problem found while crawling, e.g. a truncated page.
+type CrawlWarning struct {
+	Code    string `json:"code"` // TRUNCATED, SPIDER_TRAP, TIMED_OUT
+	Message string `json:"message"`
+}
+
+// Sizes of the crawl columns that hold values taken as written from the crawled page or its headers.
+// Longer values are cut to fit, so that they can't keep the result from being saved.
+const (
+	MaxContentTypeLength = 100
+	MaxDoctypeLength     = 512
+	MaxEncodingLength    = 40
+)
+
 // Crawl represents the results of a single crawl operation performed by a user.
 type Crawl struct {
 	gorm.Model
 	UserID           uint           `gorm:"not null" json:"user_id"`
+	BatchID          *uint          `gorm:"index" json:"batch_id,omitempty"` // Set for crawls started from a batch
 	URL              string         `gorm:"type:varchar(2048);not null" json:"url"`
-	Status           string         `gorm:"type:varchar(20);default:'PENDING'" json:"status"` // PENDING, PROCESSING, COMPLETED, FAILED
-	HTMLVersion      string         `json:"html_version"`
+	Status           string         `gorm:"type:varchar(20);default:'PENDING'" json:"status"`       // PENDING, PROCESSING, COMPLETED, FAILED, BLOCKED, TIMED_OUT
+	Priority         string         `gorm:"type:varchar(20);default:'interactive'" json:"priority"` // interactive, bulk
+	Options          datatypes.JSON `gorm:"type:json" json:"options"`                               // Storing CrawlOptions
+	Settings         datatypes.JSON `gorm:"type:json" json:"settings"`                              // Storing the effective crawler settings of the last run
+	Credentials      string         `gorm:"type:text" json:"-"`                                     // Encrypted CrawlCredentials, never returned
+	HasCredentials   bool           `json:"has_credentials"`
+	ContentType      string         `gorm:"type:varchar(100)" json:"content_type"`       // Media type of the target, e.g. text/html, application/pdf
+	ResourceInfo     datatypes.JSON `gorm:"type:json" json:"resource_info"`              // Storing the type-specific result of non-HTML targets
+	HTMLVersion      string         `json:"html_version"`                                // e.g. HTML5; "No doctype" or "Unknown" when not declared or not recognised
+	DocumentMode     string         `gorm:"type:varchar(20)" json:"document_mode"`       // no-quirks, limited-quirks, quirks
+	Doctype          string         `gorm:"type:varchar(512)" json:"doctype,omitempty"`  // The doctype as written
+	Encoding         string         `gorm:"type:varchar(40)" json:"encoding"`            // Encoding the page was decoded with, e.g. utf-8, shift_jis
+	EncodingSource   string         `gorm:"type:varchar(10)" json:"encoding_source"`     // bom, header, meta, detected, default
+	EncodingWarning  string         `gorm:"type:text" json:"encoding_warning,omitempty"` // Declared and actual encodings disagree
 	Title            string         `json:"title"`
 	HeadingCounts    datatypes.JSON `gorm:"type:json" json:"heading_counts"` // Storing map[string]int
 	InternalLinks    int            `json:"internal_links"`
 	ExternalLinks    int            `json:"external_links"`
 	BrokenLinks      int            `json:"broken_links"`
 	BrokenLinkDetail datatypes.JSON `gorm:"type:json" json:"broken_link_detail"` // Storing []BrokenLinkDetail
+	BlockedLinks     int            `json:"blocked_links"`
 	TotalLinks       int            `json:"total_links"`
+	PagesCrawled     int            `json:"pages_crawled"` // Pages fetched by a site crawl, the target included
 	HasLoginForm     bool           `json:"has_login_form"`
+	Warnings         datatypes.JSON `gorm:"type:json" json:"warnings"`       // Storing []CrawlWarning
+	SitemapReport    datatypes.JSON `gorm:"type:json" json:"sitemap_report"` // Storing the sitemap findings of crawls that check sitemaps
+	OrphanPages      datatypes.JSON `gorm:"type:json" json:"orphan_pages"`   // Storing []string, sitemap URLs no page of a site crawl links to
 	ProcessingTimeMs int64          `json:"processing_time_ms"`
+	ResultKey        string         `gorm:"type:char(64);index" json:"-"` // Identifies crawls of the same URL with the same settings, empty for authenticated crawls
+	ReusedFromID     *uint          `json:"reused_from_id,omitempty"`     // Crawl whose recent result this one reused instead of crawling
 	ErrorMessage     string         `gorm:"type:text" json:"error_message,omitempty"`
 }
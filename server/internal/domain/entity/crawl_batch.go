@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Outcomes of a single row of a batch.
+const (
+	BatchRowAccepted = "accepted"
+	BatchRowRejected = "rejected"
+)
+
+// BatchRow is a helper struct for the outcome of a single submitted URL of a batch.
+type BatchRow struct {
+	Row     int    `json:"row"` // 1-based position in the submitted list or file
+	URL     string `json:"url"`
+	Status  string `json:"status"`           // accepted, rejected
+	Reason  string `json:"reason,omitempty"` // Why the row was rejected
+	CrawlID uint   `json:"crawl_id,omitempty"`
+}
+
+// CrawlBatch groups the crawls started from a single list of URLs.
+type CrawlBatch struct {
+	gorm.Model
+	UserID   uint           `gorm:"not null;index" json:"user_id"`
+	Source   string         `gorm:"type:varchar(10);not null" json:"source"` // json, csv, txt
+	FileName string         `gorm:"type:varchar(255)" json:"file_name,omitempty"`
+	Rows     int            `json:"rows"`
+	Accepted int            `json:"accepted"`
+	Rejected int            `json:"rejected"`
+	Results  datatypes.JSON `gorm:"type:json" json:"results"` // Storing []BatchRow
+
+	CompletedAt *time.Time `json:"completed_at,omitempty"` // When the last crawl of the batch finished
+}
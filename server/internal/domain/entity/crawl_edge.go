@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// CrawlEdge represents a link between two pages of a site crawl, the edges of its link graph.
+// Edges are replaced together with the pages on every (re-)run, so they are hard-deleted too.
+type CrawlEdge struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	CrawlID    uint      `gorm:"not null;index" json:"crawl_id"`
+	SourceURL  string    `gorm:"type:text;not null" json:"source_url"` // Normalised URL of the linking page
+	TargetURL  string    `gorm:"type:text;not null" json:"target_url"` // Normalised URL of the linked page
+	AnchorText string    `gorm:"type:text" json:"anchor_text"`
+	Redirect   bool      `gorm:"not null;default:false" json:"redirect"` // The source redirects to the target
+}
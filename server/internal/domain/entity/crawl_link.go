@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// CrawlLink represents a single link discovered on a crawled page together with its check result.
+// Links are replaced wholesale on every (re-)run, so they are hard-deleted and don't embed gorm.Model.
+type CrawlLink struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	CrawlID        uint      `gorm:"not null;index" json:"crawl_id"`
+	URL            string    `gorm:"type:text;not null" json:"url"`
+	NormalizedURL  string    `gorm:"type:text;not null" json:"normalized_url"`
+	LinkType       string    `gorm:"type:varchar(10);not null;index" json:"link_type"` // internal, external
+	StatusCode     int       `gorm:"index" json:"status_code"`                         // 0 when the check failed without a response
+	CheckError     string    `gorm:"type:text" json:"check_error,omitempty"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	Attempts       int       `gorm:"not null;default:1" json:"attempts"`
+	Blocked        bool      `gorm:"not null;default:false" json:"blocked"` // Refused by the outbound network policy
+	RedirectTarget string    `gorm:"type:text" json:"redirect_target,omitempty"`
+	AnchorText     string    `gorm:"type:text" json:"anchor_text"`
+}
+
+// IsBroken reports whether the link check failed or returned an error status code.
+// Blocked links were never requested and don't count as broken.
+func (l *CrawlLink) IsBroken() bool {
+	return !l.Blocked && (l.StatusCode == 0 || l.StatusCode >= 400)
+}
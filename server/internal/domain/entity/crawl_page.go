@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// CrawlPage represents a page reached by a site crawl, with what is needed to judge its indexability.
+// Pages are replaced wholesale on every (re-)run, so they are hard-deleted and don't embed gorm.Model.
+type CrawlPage struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CrawlID       uint       `gorm:"not null;index" json:"crawl_id"`
+	URL           string     `gorm:"type:text;not null" json:"url"`
+	NormalizedURL string     `gorm:"type:text;not null" json:"normalized_url"`
+	Depth         int        `gorm:"not null" json:"depth"` // Links followed from the crawl's target
+	StatusCode    int        `json:"status_code"`           // 0 when the fetch failed without a response
+	RedirectURL   string     `gorm:"type:text" json:"redirect_url,omitempty"`
+	ContentType   string     `gorm:"type:varchar(100)" json:"content_type"`
+	Title         string     `gorm:"type:text" json:"title"`
+	LastModified  *time.Time `json:"last_modified,omitempty"` // From the Last-Modified header
+	Noindex       bool       `gorm:"not null;default:false" json:"noindex"`
+	CanonicalURL  string     `gorm:"type:text" json:"canonical_url,omitempty"`
+	Indexable     bool       `gorm:"not null;default:false;index" json:"indexable"` // A 200 HTML page that is neither noindex nor canonicalised elsewhere
+	FetchError    string     `gorm:"type:text" json:"fetch_error,omitempty"`
+	Truncated     string     `gorm:"type:text" json:"truncated,omitempty"`   // The limits the page hit, so that it was analysed only in part
+	Inlinks       int        `gorm:"not null;default:0" json:"inlinks"`      // Crawled pages linking to the page
+	Outlinks      int        `gorm:"not null;default:0" json:"outlinks"`     // Internal links and redirect of the page, crawled or not
+	PageRank      float64    `gorm:"not null;default:0" json:"pagerank"`     // Internal PageRank; the ranks of a crawl's pages sum up to 1
+	DeadEnd       bool       `gorm:"not null;default:false" json:"dead_end"` // A 200 HTML page without internal links
+}
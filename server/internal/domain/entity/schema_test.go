@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+// URLs come from crawled pages and have no set length, so a single long one must not make the
+// insert of a crawl's links, pages or edges fail.
+func TestURLColumnsAreUnbounded(t *testing.T) {
+	for _, model := range []any{&CrawlLink{}, &CrawlPage{}, &CrawlEdge{}} {
+		s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("parsing %T: %v", model, err)
+		}
+		for _, field := range s.Fields {
+			if !strings.HasSuffix(field.DBName, "url") && field.DBName != "redirect_target" {
+				continue
+			}
+			if field.DataType != "text" {
+				t.Errorf("%s.%s is %s, want text", s.Name, field.Name, field.DataType)
+			}
+		}
+	}
+}
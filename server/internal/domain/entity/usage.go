@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// DailyUsage represents what a user consumed of their daily quotas on a single UTC day.
+type DailyUsage struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_usage_user_day" json:"user_id"`
+	Day        time.Time `gorm:"type:date;not null;uniqueIndex:idx_usage_user_day" json:"day"`
+	Crawls     int64     `gorm:"not null;default:0" json:"crawls"`      // Crawls started or re-run, reused results included
+	LinkChecks int64     `gorm:"not null;default:0" json:"link_checks"` // Links checked by crawls, counted when a crawl is about to check them
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+)
+
+// CrawlBatchRepository defines the interface for batches of crawls.
+type CrawlBatchRepository interface {
+	// Create saves a new batch record to the database.
+	Create(ctx context.Context, batch *entity.CrawlBatch) error
+
+	// Update modifies an existing batch record in the database.
+	Update(ctx context.Context, batch *entity.CrawlBatch) error
+
+	// FindByID retrieves a single batch record by its ID and user ID.
+	FindByID(ctx context.Context, id, userID uint) (*entity.CrawlBatch, error)
+
+	// MarkCompleted records when a batch completed, reporting false if it was already marked.
+	MarkCompleted(ctx context.Context, id uint, at time.Time) (bool, error)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+)
+
+// CrawlLinkFilter narrows down the links returned for a crawl.
+type CrawlLinkFilter struct {
+	LinkType string // "internal", "external" or empty for both
+	Status   string // "ok", "redirect", "broken", "error", "blocked", an exact status code, or empty for all
+	Page     int    // 1-based page number
+	PageSize int    // Number of links per page
+}
+
+// CrawlLinkRepository defines the interface for crawl link data operations.
+type CrawlLinkRepository interface {
+	// ReplaceForCrawl deletes all links stored for a crawl and saves the given ones in their place.
+	ReplaceForCrawl(ctx context.Context, crawlID uint, links []entity.CrawlLink) error
+
+	// FindByCrawlID retrieves a filtered page of links for a crawl along with the total number of matches.
+	FindByCrawlID(ctx context.Context, crawlID uint, filter CrawlLinkFilter) ([]entity.CrawlLink, int64, error)
+
+	// FindAllByCrawlID retrieves every link of a crawl in the order they were stored.
+	FindAllByCrawlID(ctx context.Context, crawlID uint) ([]entity.CrawlLink, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+)
+
+// CrawlPageRepository defines the interface for the pages of site crawls and the link graph between them.
+type CrawlPageRepository interface {
+	// ReplaceForCrawl deletes all pages and edges stored for a crawl and saves the given ones in their place.
+	ReplaceForCrawl(ctx context.Context, crawlID uint, pages []entity.CrawlPage, edges []entity.CrawlEdge) error
+
+	// FindIndexable retrieves the indexable pages of a crawl in the order they were crawled.
+	FindIndexable(ctx context.Context, crawlID uint) ([]entity.CrawlPage, error)
+
+	// FindGraph retrieves all pages of a crawl and the edges between them.
+	FindGraph(ctx context.Context, crawlID uint) ([]entity.CrawlPage, []entity.CrawlEdge, error)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
 )
@@ -11,15 +12,36 @@ type CrawlRepository interface {
 	// Create saves a new crawl record to the database.
 	Create(ctx context.Context, crawl *entity.Crawl) error
 
+	// CreateWithinActiveLimit saves a new pending crawl unless its user already has limit crawls
+	// pending or processing, and reports whether it did. A limit of zero disables the check.
+	CreateWithinActiveLimit(ctx context.Context, crawl *entity.Crawl, limit int64) (bool, error)
+
 	// FindByUserID retrieves all crawl records for a specific user.
 	FindByUserID(ctx context.Context, userID uint) ([]entity.Crawl, error)
 
 	// FindByID retrieves a single crawl record by its ID and user ID.
 	FindByID(ctx context.Context, id, userID uint) (*entity.Crawl, error)
 
+	// FindLatestCompletedByResultKey retrieves the most recent completed crawl, of any user, with the
+	// given result key that was updated after since.
+	FindLatestCompletedByResultKey(ctx context.Context, resultKey string, since time.Time) (*entity.Crawl, error)
+
+	// CountActiveByUserID counts the crawls of a user that are pending or processing.
+	CountActiveByUserID(ctx context.Context, userID uint) (int64, error)
+
+	// CountByStatusForBatch counts the crawls of a batch per status.
+	CountByStatusForBatch(ctx context.Context, batchID uint) (map[string]int64, error)
+
 	// Update modifies an existing crawl record in the database.
 	Update(ctx context.Context, crawl *entity.Crawl) error
 
+	// UpdateWithinActiveLimit saves a crawl made pending again unless its user already has limit
+	// other crawls pending or processing, and reports whether it did. A limit of zero disables the check.
+	UpdateWithinActiveLimit(ctx context.Context, crawl *entity.Crawl, limit int64) (bool, error)
+
+	// UpdateStatus sets only the status and error message of a crawl.
+	UpdateStatus(ctx context.Context, id uint, status, errorMessage string) error
+
 	// Delete removes a crawl record by its ID and user ID.
 	Delete(ctx context.Context, id, userID uint) error
 
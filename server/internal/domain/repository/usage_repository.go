@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+)
+
+// UsageRepository defines the interface for the daily quota usage of users.
+type UsageRepository interface {
+	// Add adds crawls and link checks to what a user consumed on a day, creating the day as needed.
+	Add(ctx context.Context, userID uint, day time.Time, crawls, linkChecks int64) error
+
+	// ReserveCrawl counts a crawl against a user's usage of a day unless that would go over limit
+	// (0 for none), reporting whether it was counted. The check and the count are atomic.
+	ReserveCrawl(ctx context.Context, userID uint, day time.Time, limit int64) (bool, error)
+
+	// ReserveLinkChecks counts up to n link checks against a user's usage of a day without going
+	// over limit (0 for none), returning how many were counted. The check and the count are atomic.
+	ReserveLinkChecks(ctx context.Context, userID uint, day time.Time, n, limit int64) (int64, error)
+
+	// FindByDay retrieves what a user consumed on a day; days without usage are returned empty.
+	FindByDay(ctx context.Context, userID uint, day time.Time) (*entity.DailyUsage, error)
+}
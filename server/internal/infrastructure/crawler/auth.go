@@ -0,0 +1,157 @@
+package crawler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// forbiddenHeaders are managed by the HTTP client and can't be overridden by a crawl.
+var forbiddenHeaders = map[string]struct{}{
+	"Host":              {},
+	"Content-Length":    {},
+	"Connection":        {},
+	"Transfer-Encoding": {},
+	"Upgrade":           {},
+}
+
+// Auth holds the credentials a crawl sends to the target site.
+type Auth struct {
+	Headers      map[string]string
+	Cookies      []*http.Cookie
+	Username     string // HTTP basic auth
+	Password     string
+	BearerToken  string
+	ApplyToLinks bool // Also send the credentials when checking links on the target's origin
+}
+
+// apply adds the headers, basic auth and bearer token to a request's headers.
+// Cookies are sent through the crawl's jar instead.
+func (a *Auth) apply(header http.Header) {
+	for name, value := range a.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if _, forbidden := forbiddenHeaders[name]; !forbidden {
+			header.Set(name, value)
+		}
+	}
+	if a.Username != "" || a.Password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+	if a.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+}
+
+// strip removes the headers apply adds from a request's headers.
+func (a *Auth) strip(header http.Header) {
+	for name := range a.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if _, forbidden := forbiddenHeaders[name]; !forbidden {
+			header.Del(name)
+		}
+	}
+	if a.Username != "" || a.Password != "" || a.BearerToken != "" {
+		header.Del("Authorization")
+	}
+}
+
+// maxRedirects is the number of redirects followed, the same as net/http's default.
+const maxRedirects = 10
+
+// session holds the per-crawl state: the transport chosen by the crawl's proxy settings
+// and what is needed to send authenticated requests.
+type session struct {
+	ctx        context.Context // Ends when the crawl has to stop, e.g. at its deadline
+	progress   *progressTracker
+	target     *url.URL
+	auth       *Auth
+	transport  http.RoundTripper
+	client     *http.Client
+	authClient *http.Client // Carries the crawl's cookie jar; nil when the crawl has no credentials
+	jar        http.CookieJar
+	close      func()
+}
+
+// newSession prepares the transport, cookie jar and clients of a crawl.
+func (wc *WebCrawler) newSession(ctx context.Context, target *url.URL, opts Options) (*session, error) {
+	transport, closeTransport, err := wc.transportFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{ctx: ctx, progress: newProgressTracker(opts.Progress), target: target, auth: opts.Auth, transport: transport, client: wc.httpClient, close: closeTransport}
+	if transport != wc.transport {
+		// Timeouts are applied per request from the crawl's options.
+		s.client = &http.Client{Transport: transport}
+	}
+	if opts.Auth == nil {
+		return s, nil
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		closeTransport()
+		return nil, err
+	}
+	jar.SetCookies(target, opts.Auth.Cookies)
+	s.jar = jar
+	s.authClient = &http.Client{Transport: transport, Jar: jar, CheckRedirect: s.checkRedirect}
+	return s, nil
+}
+
+// checkRedirect keeps the crawl's credentials from following a redirect off the target's origin.
+// net/http only drops Authorization and Cookie, and only when the domain changes, so every header
+// the credentials add is removed once a redirect leaves the origin.
+func (s *session) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if s.auth != nil && !sameOrigin(s.target, req.URL) {
+		s.auth.strip(req.Header)
+	}
+	return nil
+}
+
+// clientFor returns the client to use for link: the crawl's cookie-carrying client when
+// credentials apply to it, the plain one otherwise.
+func (s *session) clientFor(link string, isTarget bool) *http.Client {
+	if s.authenticates(link, isTarget) {
+		return s.authClient
+	}
+	return s.client
+}
+
+// authenticates reports whether credentials should be sent to link.
+// They only ever go to the target's own origin, and to links on it only if requested.
+func (s *session) authenticates(link string, isTarget bool) bool {
+	if s.auth == nil || (!isTarget && !s.auth.ApplyToLinks) {
+		return false
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return sameOrigin(s.target, parsed)
+}
+
+// sameOrigin compares scheme, host and effective port of two URLs.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		canonicalHostname(a.Host) == canonicalHostname(b.Host) &&
+		effectivePort(a) == effectivePort(b)
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
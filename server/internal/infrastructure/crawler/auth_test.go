@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// headerRecorder serves every request and records the credential headers it received.
+type headerRecorder struct {
+	mu      sync.Mutex
+	leaked  []string
+	handler http.HandlerFunc
+}
+
+func (h *headerRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	for _, name := range []string{"X-Api-Key", "Authorization"} {
+		if value := r.Header.Get(name); value != "" {
+			h.leaked = append(h.leaked, fmt.Sprintf("%s %s: %s", r.Method, name, value))
+		}
+	}
+	h.mu.Unlock()
+	if h.handler != nil {
+		h.handler(w, r)
+	}
+}
+
+func TestCredentialsDontFollowCrossOriginRedirects(t *testing.T) {
+	thirdParty := &headerRecorder{}
+	other := httptest.NewServer(thirdParty)
+	defer other.Close()
+
+	target := &headerRecorder{handler: func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/away", "/moved":
+			http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<!DOCTYPE html><html><body><a href="/away">away</a></body></html>`)
+		}
+	}}
+	server := httptest.NewServer(target)
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy}, NewMemoryLinkCache(100, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"same-origin redirect, then a link that redirects away", "/start"},
+		{"target that redirects away", "/moved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thirdParty.leaked, target.leaked = nil, nil
+			opts := wc.DefaultOptions()
+			opts.Auth = &Auth{
+				Headers:      map[string]string{"X-API-Key": "secret"},
+				BearerToken:  "token",
+				ApplyToLinks: true,
+			}
+			if _, err := wc.CrawlPage(context.Background(), server.URL+tt.path, opts); err != nil {
+				t.Fatalf("CrawlPage: %v", err)
+			}
+			if len(target.leaked) == 0 {
+				t.Error("the target never received the credentials")
+			}
+			if len(thirdParty.leaked) > 0 {
+				t.Errorf("credentials leaked to another origin: %v", thirdParty.leaked)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LinkCheck is the outcome of a HEAD request against a link.
+type LinkCheck struct {
+	StatusCode   int
+	Error        string
+	RedirectURL  string
+	ResponseTime time.Duration
+	Attempts     int
+	Blocked      bool // The network policy refused the connection
+}
+
+// LinkCache stores link check outcomes so the same link isn't fetched on every crawl. Outcomes
+// are keyed by the link and what its answer depends on, see linkCacheKey.
+// Implementations must be safe for concurrent use.
+type LinkCache interface {
+	// Get returns the cached outcome for a key, or false if it is missing or expired.
+	Get(key string) (LinkCheck, bool)
+	// Set stores the outcome for a key. Outcomes whose TTL is zero are not stored.
+	Set(key string, check LinkCheck)
+}
+
+// CacheTTLs defines how long each kind of link check outcome stays cached.
+type CacheTTLs struct {
+	Success     time.Duration // 2xx and 3xx responses
+	ClientError time.Duration // 4xx responses
+	ServerError time.Duration // 5xx responses
+	Error       time.Duration // Network errors and timeouts, i.e. no response at all
+}
+
+// DefaultCacheTTLs keeps healthy links for a day but re-checks failures quickly.
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		Success:     24 * time.Hour,
+		ClientError: time.Hour,
+		ServerError: 5 * time.Minute,
+		Error:       time.Minute,
+	}
+}
+
+// For returns the TTL that applies to a link check outcome.
+func (t CacheTTLs) For(check LinkCheck) time.Duration {
+	switch {
+	case check.StatusCode == 0:
+		return t.Error
+	case check.StatusCode >= 500:
+		return t.ServerError
+	case check.StatusCode >= 400:
+		return t.ClientError
+	default:
+		return t.Success
+	}
+}
+
+// memoryCacheEntry is a single element of the in-memory LRU list.
+type memoryCacheEntry struct {
+	key       string
+	check     LinkCheck
+	expiresAt time.Time
+}
+
+// MemoryLinkCache is an in-process LinkCache bounded in size with least-recently-used eviction.
+type MemoryLinkCache struct {
+	ttls       CacheTTLs
+	maxEntries int
+	mu         sync.Mutex
+	order      *list.List // Front is the most recently used entry
+	entries    map[string]*list.Element
+}
+
+// NewMemoryLinkCache creates an in-memory cache holding at most maxEntries links.
+func NewMemoryLinkCache(maxEntries int, ttls CacheTTLs) *MemoryLinkCache {
+	return &MemoryLinkCache{
+		ttls:       ttls,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached outcome for a key and marks it as recently used.
+func (c *MemoryLinkCache) Get(key string) (LinkCheck, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return LinkCheck{}, false
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return LinkCheck{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.check, true
+}
+
+// Set stores the outcome for a key, evicting the least recently used entries when full.
+func (c *MemoryLinkCache) Set(key string, check LinkCheck) {
+	ttl := c.ttls.For(check)
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*memoryCacheEntry)
+		entry.check, entry.expiresAt = check, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{key: key, check: check, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Len returns the number of entries currently held, including expired ones not yet evicted.
+func (c *MemoryLinkCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *MemoryLinkCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*memoryCacheEntry).key)
+}
@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dbCachePurgeInterval is the number of writes between two purges of expired and excess rows.
+const dbCachePurgeInterval = 1000
+
+// linkCacheEntry is the row stored by DBLinkCache. Keys can be longer than MySQL's
+// index limit, so rows are keyed by the SHA-256 of the key.
+type linkCacheEntry struct {
+	KeyHash        string    `gorm:"type:char(64);primaryKey"`
+	URL            string    `gorm:"type:text;not null"` // The key, which holds the link
+	StatusCode     int       `gorm:"not null"`
+	Error          string    `gorm:"type:text"`
+	RedirectURL    string    `gorm:"type:text"`
+	ResponseTimeMs int64     `gorm:"not null"`
+	Attempts       int       `gorm:"not null;default:1"`
+	CheckedAt      time.Time `gorm:"index"` // When the outcome was stored, to evict the oldest rows first
+	ExpiresAt      time.Time `gorm:"not null;index"`
+}
+
+// TableName overrides the default GORM table name.
+func (linkCacheEntry) TableName() string {
+	return "link_status_cache"
+}
+
+// DBLinkCache is a LinkCache backed by the database, so cached outcomes survive
+// restarts and are shared by every API instance pointing at the same database.
+// Like the in-memory cache it holds a bounded number of rows, evicting the oldest ones.
+type DBLinkCache struct {
+	db         *gorm.DB
+	ttls       CacheTTLs
+	maxEntries int
+	writes     atomic.Int64
+}
+
+// NewDBLinkCache creates a database-backed cache holding about maxEntries links and migrates its
+// table. Rows beyond maxEntries are deleted with the expired ones, every dbCachePurgeInterval writes.
+func NewDBLinkCache(db *gorm.DB, maxEntries int, ttls CacheTTLs) (*DBLinkCache, error) {
+	if err := db.AutoMigrate(&linkCacheEntry{}); err != nil {
+		return nil, err
+	}
+	return &DBLinkCache{db: db, ttls: ttls, maxEntries: maxEntries}, nil
+}
+
+// Get returns the cached outcome for a key if a row exists and hasn't expired.
+func (c *DBLinkCache) Get(key string) (LinkCheck, bool) {
+	var entry linkCacheEntry
+	err := c.db.Where("key_hash = ? AND expires_at > ?", hashKey(key), time.Now()).Take(&entry).Error
+	if err != nil {
+		return LinkCheck{}, false
+	}
+	return LinkCheck{
+		StatusCode:   entry.StatusCode,
+		Error:        entry.Error,
+		RedirectURL:  entry.RedirectURL,
+		ResponseTime: time.Duration(entry.ResponseTimeMs) * time.Millisecond,
+		Attempts:     entry.Attempts,
+	}, true
+}
+
+// Set upserts the outcome for a key and periodically purges expired rows to bound the table size.
+func (c *DBLinkCache) Set(key string, check LinkCheck) {
+	ttl := c.ttls.For(check)
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	entry := linkCacheEntry{
+		KeyHash:        hashKey(key),
+		URL:            key,
+		StatusCode:     check.StatusCode,
+		Error:          check.Error,
+		RedirectURL:    check.RedirectURL,
+		ResponseTimeMs: check.ResponseTime.Milliseconds(),
+		Attempts:       check.Attempts,
+		CheckedAt:      time.Now(),
+		ExpiresAt:      time.Now().Add(ttl),
+	}
+	if err := c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entry).Error; err != nil {
+		log.Printf("Error caching link status for %s: %v", key, err)
+		return
+	}
+
+	if c.writes.Add(1)%dbCachePurgeInterval == 0 {
+		c.PurgeExpired()
+		c.PurgeExcess()
+	}
+}
+
+// PurgeExpired deletes all expired rows.
+func (c *DBLinkCache) PurgeExpired() {
+	if err := c.db.Where("expires_at <= ?", time.Now()).Delete(&linkCacheEntry{}).Error; err != nil {
+		log.Printf("Error purging expired link cache entries: %v", err)
+	}
+}
+
+// PurgeExcess deletes the oldest rows beyond maxEntries.
+func (c *DBLinkCache) PurgeExcess() {
+	// The newest row that doesn't fit is the cutoff; it and every older row go. Rows stored before
+	// checked_at existed have none and count as the oldest.
+	var cutoff []sql.NullTime
+	err := c.db.Model(&linkCacheEntry{}).Order("checked_at DESC").Offset(c.maxEntries).Limit(1).Pluck("checked_at", &cutoff).Error
+	if err != nil {
+		log.Printf("Error finding excess link cache entries: %v", err)
+		return
+	}
+	if len(cutoff) == 0 {
+		return
+	}
+	query := c.db.Where("checked_at IS NULL")
+	if cutoff[0].Valid {
+		query = query.Or("checked_at <= ?", cutoff[0].Time)
+	}
+	if err := query.Delete(&linkCacheEntry{}).Error; err != nil {
+		log.Printf("Error purging excess link cache entries: %v", err)
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,197 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Where the encoding used to decode a page came from.
+const (
+	EncodingSourceBOM      = "bom"
+	EncodingSourceHeader   = "header"
+	EncodingSourceMeta     = "meta"
+	EncodingSourceDetected = "detected"
+	EncodingSourceDefault  = "default"
+)
+
+// prescanLimit is how far into the body a meta charset declaration is looked for, as browsers do.
+const prescanLimit = 1024
+
+// EncodingInfo describes how a page's bytes were decoded to UTF-8.
+type EncodingInfo struct {
+	Name    string `json:"name"`              // WHATWG name of the encoding, e.g. "utf-8", "shift_jis", "windows-1251"
+	Source  string `json:"source"`            // bom, header, meta, detected or default
+	Warning string `json:"warning,omitempty"` // Set when the declared encodings disagree with each other or with the bytes
+}
+
+var boms = []struct {
+	prefix []byte
+	name   string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// decodeBody converts a page body to UTF-8. The encoding is taken from the byte order mark,
+// the Content-Type charset or a meta charset declaration, in that order, and sniffed from the
+// bytes when none is declared. A declaration the bytes contradict is reported and overridden.
+func decodeBody(body []byte, contentType string) ([]byte, EncodingInfo) {
+	headerName := headerCharset(contentType)
+	metaName := metaCharset(body)
+
+	for _, bom := range boms {
+		if bytes.HasPrefix(body, bom.prefix) {
+			info := EncodingInfo{Name: bom.name, Source: EncodingSourceBOM}
+			if declared := firstNonEmpty(headerName, metaName); declared != "" && declared != bom.name {
+				info.Warning = fmt.Sprintf("byte order mark indicates %s but %s is declared", bom.name, declared)
+			}
+			return decodeWith(body[len(bom.prefix):], bom.name), info
+		}
+	}
+
+	var info EncodingInfo
+	var warnings []string
+	switch {
+	case headerName != "":
+		info = EncodingInfo{Name: headerName, Source: EncodingSourceHeader}
+		if metaName != "" && metaName != headerName {
+			warnings = append(warnings, fmt.Sprintf("Content-Type declares %s but the meta tag declares %s", headerName, metaName))
+		}
+	case metaName != "":
+		info = EncodingInfo{Name: metaName, Source: EncodingSourceMeta}
+	default:
+		info = sniffEncoding(body)
+	}
+
+	// Verify the declaration against the bytes; a wrong declaration garbles every non-ASCII character.
+	if info.Source == EncodingSourceHeader || info.Source == EncodingSourceMeta {
+		valid := utf8.Valid(body)
+		switch {
+		case info.Name == "utf-8" && !valid:
+			actual := sniffEncoding(body)
+			warnings = append(warnings, fmt.Sprintf("%s is declared but the body is not valid UTF-8, decoded as %s", info.Name, actual.Name))
+			info.Name, info.Source = actual.Name, actual.Source
+		case info.Name != "utf-8" && valid && hasNonASCII(body):
+			warnings = append(warnings, fmt.Sprintf("%s is declared but the body is valid UTF-8, decoded as utf-8", info.Name))
+			info.Name, info.Source = "utf-8", EncodingSourceDetected
+		}
+	}
+	info.Warning = strings.Join(warnings, "; ")
+	return decodeWith(body, info.Name), info
+}
+
+// sniffEncoding guesses the encoding of an undeclared body.
+func sniffEncoding(body []byte) EncodingInfo {
+	if utf8.Valid(body) {
+		return EncodingInfo{Name: "utf-8", Source: EncodingSourceDetected}
+	}
+	if result, err := chardet.NewHtmlDetector().DetectBest(body); err == nil {
+		if _, name := charset.Lookup(result.Charset); name != "" {
+			return EncodingInfo{Name: name, Source: EncodingSourceDetected}
+		}
+	}
+	// The HTML standard's fallback for legacy content.
+	return EncodingInfo{Name: "windows-1252", Source: EncodingSourceDefault}
+}
+
+// headerCharset returns the canonical name of the charset parameter of a Content-Type header.
+func headerCharset(contentType string) string {
+	_, name := charset.Lookup(headerCharsetLabel(contentType))
+	return name
+}
+
+// metaCharset looks for <meta charset> or <meta http-equiv="Content-Type"> near the start of the body.
+func metaCharset(body []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(body[:min(len(body), prescanLimit)]))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.Data != "meta" {
+				continue
+			}
+			var label, httpEquiv, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "charset":
+					label = attr.Val
+				case "http-equiv":
+					httpEquiv = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+			if label == "" && strings.EqualFold(httpEquiv, "content-type") {
+				label = headerCharsetLabel(content)
+			}
+			if _, name := charset.Lookup(label); name != "" {
+				// A meta tag can't truthfully declare UTF-16: it was readable as ASCII.
+				if strings.HasPrefix(name, "utf-16") {
+					return "utf-8"
+				}
+				return name
+			}
+		}
+	}
+}
+
+// headerCharsetLabel extracts the raw charset label of a Content-Type value.
+func headerCharsetLabel(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// decodeWith decodes body from the named encoding, leaving it untouched for UTF-8.
+func decodeWith(body []byte, name string) []byte {
+	var enc encoding.Encoding
+	switch name {
+	case "utf-8":
+		return body
+	case "utf-16be":
+		enc = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case "utf-16le":
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	default:
+		if enc, _ = charset.Lookup(name); enc == nil {
+			return body
+		}
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+func hasNonASCII(body []byte) bool {
+	for _, b := range body {
+		if b >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
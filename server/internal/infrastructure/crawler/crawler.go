@@ -1,7 +1,10 @@
 package crawler
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
@@ -16,73 +19,267 @@ import (
 type PageInfo struct {
 	URL              string             `json:"url"`
 	HTMLVersion      string             `json:"html_version"`
+	DocumentMode     string             `json:"document_mode"`
+	Doctype          string             `json:"doctype"`
 	Title            string             `json:"title"`
 	HeadingCounts    map[string]int     `json:"heading_counts"`
 	InternalLinks    int                `json:"internal_links"`
 	ExternalLinks    int                `json:"external_links"`
 	BrokenLinks      int                `json:"broken_links"`
 	BrokenLinkDetail []BrokenLinkStatus `json:"broken_link_detail"`
+	BlockedLinks     int                `json:"blocked_links"`
 	TotalLinks       int                `json:"total_links"`
 	HasLoginForm     bool               `json:"has_login_form"`
+	Encoding         EncodingInfo       `json:"encoding"`
+	ContentType      string             `json:"content_type"`
+	Resource         *ResourceInfo      `json:"resource,omitempty"` // Set for targets that aren't HTML pages
+	Warnings         []Warning          `json:"warnings,omitempty"`
+	Sitemap          *SitemapReport     `json:"sitemap,omitempty"` // Set when sitemaps were checked
+	Pages            []SitePage         `json:"pages,omitempty"`   // Set by site crawls
+	Graph            *SiteGraph         `json:"graph,omitempty"`   // Internal link graph of site crawls
+	Links            []LinkResult       `json:"links"`
+	Requests         int                `json:"requests"` // Link checks, sitemap URL checks and site pages charged to the link budget
 	ProcessingTime   time.Duration      `json:"processing_time"`
 }
 
+// LinkResult holds the outcome of checking a single unique link found on the page.
+type LinkResult struct {
+	URL           string        `json:"url"`
+	NormalizedURL string        `json:"normalized_url"`
+	IsInternal    bool          `json:"is_internal"`
+	AnchorText    string        `json:"anchor_text"`
+	StatusCode    int           `json:"status_code"`
+	Error         string        `json:"error,omitempty"`
+	RedirectURL   string        `json:"redirect_url,omitempty"`
+	ResponseTime  time.Duration `json:"response_time"`
+	Attempts      int           `json:"attempts"`
+	Blocked       bool          `json:"blocked"`
+}
+
+// IsBroken reports whether the link check failed or returned an error status code.
+// Links blocked by the network policy were never requested and don't count as broken.
+func (l LinkResult) IsBroken() bool {
+	return !l.Blocked && (l.StatusCode == 0 || l.StatusCode >= 400)
+}
+
 // BrokenLinkStatus holds details of a broken link.
 type BrokenLinkStatus struct {
 	URL        string `json:"url"`
 	StatusCode int    `json:"status_code"`
 }
 
+// foundLink is a link as it appears in the page, before it is checked.
+type foundLink struct {
+	URL           string
+	NormalizedURL string
+	AnchorText    string
+}
+
 // WebCrawler is the main crawler struct.
 type WebCrawler struct {
-	httpClient *http.Client
-	linkCache  map[string]BrokenLinkStatus
-	cacheMux   sync.RWMutex
-	userAgent  string
+	cfg            Config
+	httpClient     *http.Client
+	transport      http.RoundTripper
+	proxyTransport http.RoundTripper // Rotates through the proxy pool; nil without proxies
+	limiter        *HostLimiter
+	linkCache      LinkCache
 }
 
-// NewWebCrawler creates a new crawler instance.
-func NewWebCrawler() *WebCrawler {
-	return &WebCrawler{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-		linkCache:  make(map[string]BrokenLinkStatus),
-		userAgent:  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+// NewWebCrawler creates a new crawler instance. Link check outcomes are stored in linkCache,
+// and every outbound request, page fetches and link checks alike, goes through limiter.
+func NewWebCrawler(cfg Config, linkCache LinkCache, limiter *HostLimiter) *WebCrawler {
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 10 * time.Second
+	}
+	if cfg.PageTimeout <= 0 {
+		cfg.PageTimeout = 30 * time.Second
+	}
+	cfg.LinkCheckWorkers = max(cfg.LinkCheckWorkers, 1)
+	cfg.Parallelism = max(cfg.Parallelism, 1)
+	cfg.Retry.MaxAttempts = max(cfg.Retry.MaxAttempts, 1)
+	defaults := DefaultBodyLimits()
+	if cfg.Limits.MaxBodySize <= 0 {
+		cfg.Limits.MaxBodySize = defaults.MaxBodySize
+	}
+	if cfg.Limits.MaxDecompressedSize <= 0 {
+		cfg.Limits.MaxDecompressedSize = defaults.MaxDecompressedSize
+	}
+	if cfg.Limits.MaxLinksPerPage <= 0 {
+		cfg.Limits.MaxLinksPerPage = defaults.MaxLinksPerPage
+	}
+	if cfg.Limits.MaxDOMElements <= 0 {
+		cfg.Limits.MaxDOMElements = defaults.MaxDOMElements
+	}
+	if cfg.SiteMaxPages <= 0 {
+		cfg.SiteMaxPages = 500
+	}
+	if cfg.SiteMaxDepth <= 0 {
+		cfg.SiteMaxDepth = 5
+	}
+	trapDefaults := DefaultTrapLimits()
+	if cfg.Traps.MaxURLLength <= 0 {
+		cfg.Traps.MaxURLLength = trapDefaults.MaxURLLength
+	}
+	if cfg.Traps.MaxSegmentRepeats <= 0 {
+		cfg.Traps.MaxSegmentRepeats = trapDefaults.MaxSegmentRepeats
+	}
+	if cfg.Traps.MaxQueryVariants <= 0 {
+		cfg.Traps.MaxQueryVariants = trapDefaults.MaxQueryVariants
+	}
+	if cfg.Traps.MaxPagesPerPattern <= 0 {
+		cfg.Traps.MaxPagesPerPattern = trapDefaults.MaxPagesPerPattern
+	}
+	if cfg.ProxyMode == "" {
+		cfg.ProxyMode = ProxyDirect
+		if len(cfg.Proxies) > 0 {
+			cfg.ProxyMode = ProxyPool
+		}
 	}
+	wc := &WebCrawler{
+		cfg:       cfg,
+		limiter:   limiter,
+		linkCache: linkCache,
+	}
+	wc.transport = wc.wrapTransport(newGuardedTransport(cfg.Network))
+	// Timeouts are applied per request from the crawl's options.
+	wc.httpClient = &http.Client{Transport: wc.transport}
+	if len(cfg.Proxies) > 0 {
+		pool := &rotatingTransport{}
+		for _, proxyURL := range cfg.Proxies {
+			pool.transports = append(pool.transports, newProxyTransport(proxyURL, cfg.Network, true))
+		}
+		wc.proxyTransport = wc.wrapTransport(pool)
+	}
+	return wc
 }
 
-// CrawlPage performs the crawl on a single target URL.
-func (wc *WebCrawler) CrawlPage(targetURL string) (*PageInfo, error) {
-	start := time.Now()
+// wrapTransport adds the shared host limiter and the body size caps to a base transport.
+func (wc *WebCrawler) wrapTransport(base http.RoundTripper) http.RoundTripper {
+	return &limitedTransport{base: &cappedTransport{base: base, limits: wc.cfg.Limits}, limiter: wc.limiter}
+}
 
-	// Check if the target URL is an actual URL with an accessible domain
-	req, _ := http.NewRequest("HEAD", targetURL, nil)
-	req.Header.Set("User-Agent", wc.userAgent)
-	resp, err := wc.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to reach target URL: %w", err)
+// CrawlPage performs the crawl on a single target URL. A crawl that runs past opts.Timeout stops
+// and returns ErrTimedOut, along with what it got done if it fetched the page in time.
+func (wc *WebCrawler) CrawlPage(ctx context.Context, targetURL string, opts Options) (*PageInfo, error) {
+	start := time.Now()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
-	defer resp.Body.Close()
 
 	parsedBaseURL, err := url.Parse(targetURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid target URL: %w", err)
 	}
 
+	sess, err := wc.newSession(ctx, parsedBaseURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.close()
+
+	// Check if the target URL is an actual URL with an accessible domain
+	sess.progress.phase(PhaseFetching)
+	if _, _, err := wc.head(targetURL, opts, sess, true); err != nil {
+		if interrupted(ctx, err) {
+			return nil, stopped(ctx, opts)
+		}
+		return nil, fmt.Errorf("failed to reach target URL: %w", err)
+	}
+
 	info := &PageInfo{
 		URL:           targetURL,
 		HeadingCounts: make(map[string]int),
 	}
 
-	c := colly.NewCollector(colly.Async(true), colly.MaxDepth(1))
-	c.UserAgent = wc.userAgent
-	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 10, Delay: 100 * time.Millisecond})
-	c.SetRequestTimeout(30 * time.Second)
+	// Limits hit while fetching the page are recorded through the request context.
+	truncated := &truncation{}
 
-	var links []string
+	// The collector fetches synchronously, so that its callbacks, which analyse the page, run on
+	// this goroutine and a panic in them reaches the caller of CrawlPage.
+	c := colly.NewCollector(colly.MaxDepth(1))
+	c.Context = withTruncation(ctx, truncated)
+	c.MaxBodySize = 0 // The transport caps bodies and reports when it does
+	c.UserAgent = opts.UserAgent
+	c.WithTransport(sess.transport)
+	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: wc.cfg.Parallelism, Delay: opts.Delay})
+	c.SetRequestTimeout(opts.PageTimeout)
+	if sess.jar != nil {
+		c.SetCookieJar(sess.jar)
+		c.SetRedirectHandler(sess.checkRedirect)
+	}
+	c.OnRequest(func(r *colly.Request) {
+		if sess.authenticates(r.URL.String(), true) {
+			opts.Auth.apply(*r.Headers)
+		}
+	})
+
+	var links []foundLink
 	var linksMux, infoMux sync.Mutex
+	fetched := false // The page itself was received
+
+	// Retry transient failures of the page fetch itself; colly re-issues the same request.
+	pageAttempts := 1
+	var blockedErr error
+	fetchFailed := false
+	c.OnError(func(r *colly.Response, err error) {
+		fetchFailed = true
+		if ctx.Err() == nil && opts.Retry.ShouldRetry(pageAttempts, r.StatusCode, responseError(r, err)) &&
+			wait(ctx, opts.Retry.Backoff(pageAttempts)) == nil {
+			pageAttempts++
+			r.Request.Retry()
+			return
+		}
+		if isBlocked(err) {
+			blockedErr = err // e.g. the GET was redirected somewhere the HEAD wasn't
+		}
+	})
+
+	// Colly transcodes bodies whose Content-Type names a charset and ignores BOMs and meta tags.
+	// Hide the charset from it and decode the raw bytes ourselves before any analysis runs.
+	c.OnResponseHeaders(func(r *colly.Response) {
+		contentType := r.Headers.Get("Content-Type")
+		r.Ctx.Put("contentType", contentType)
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			r.Headers.Set("Content-Type", mediaType)
+		}
+	})
+	c.OnResponse(func(r *colly.Response) {
+		fetched = true
+		sess.progress.phase(PhaseParsing)
+		contentType := r.Ctx.Get("contentType")
+		mediaType, kind := detectResource(contentType, r.Body)
+		info.ContentType = mediaType
+		if kind == ResourceHTML || kind == ResourceText {
+			r.Body, info.Encoding = decodeBody(r.Body, contentType)
+		}
+		if kind == ResourceHTML {
+			r.Body = capDOMElements(r.Body, wc.cfg.Limits.MaxDOMElements, truncated)
+			// Colly only parses bodies labelled as HTML, which sniffed pages may not be.
+			r.Headers.Set("Content-Type", mediaType)
+			return
+		}
+
+		// Non-HTML targets get a type-specific analysis instead of an empty HTML one.
+		resource, title, found := analyzeResource(kind, mediaType, r.Body, parsedBaseURL)
+		infoMux.Lock()
+		info.Resource = resource
+		info.Title = title
+		infoMux.Unlock()
+		linksMux.Lock()
+		links = append(links, found...)
+		linksMux.Unlock()
+	})
 
 	c.OnHTML("html", func(e *colly.HTMLElement) {
-		info.HTMLVersion = extractHTMLVersion(string(e.Response.Body))
+		doctype := parseDoctype(e.Response.Body)
+		info.HTMLVersion = doctype.Version
+		info.DocumentMode = doctype.Mode
+		info.Doctype = doctype.Raw
 		info.HasLoginForm = hasLoginFormHTML(string(e.Response.Body))
 	})
 	c.OnHTML("title", func(e *colly.HTMLElement) {
@@ -101,117 +298,384 @@ func (wc *WebCrawler) CrawlPage(targetURL string) (*PageInfo, error) {
 		})
 	}
 	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		href := e.Attr("href")
-		if href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "javascript:") && !strings.HasPrefix(href, "mailto:") {
+		if href := e.Attr("href"); isFollowableHref(href) {
 			absURL := resolveURL(parsedBaseURL, href)
 			linksMux.Lock()
-			links = append(links, absURL)
+			links = append(links, foundLink{URL: absURL, AnchorText: strings.Join(strings.Fields(e.Text), " ")})
 			linksMux.Unlock()
 		}
 	})
 
-	if err := c.Visit(targetURL); err != nil {
+	// Visit also returns the errors of failed fetches, which OnError has handled already.
+	if err := c.Visit(targetURL); err != nil && !fetchFailed {
 		return nil, err
 	}
-	c.Wait()
+	if blockedErr != nil {
+		return nil, fmt.Errorf("failed to fetch target URL: %w", blockedErr)
+	}
+	if !fetched && ctx.Err() != nil {
+		return nil, stopped(ctx, opts)
+	}
 
-	uniqueLinks := getUniqueLinks(links)
+	uniqueLinks := getUniqueLinks(links, opts.Normalizer)
+	if len(uniqueLinks) > wc.cfg.Limits.MaxLinksPerPage {
+		truncated.record("page has more than %d links", wc.cfg.Limits.MaxLinksPerPage)
+		uniqueLinks = uniqueLinks[:wc.cfg.Limits.MaxLinksPerPage]
+	}
+	if granted := info.grantRequests(opts, len(uniqueLinks)); granted < len(uniqueLinks) {
+		truncated.record("only %d of its %d links could be checked within the link check quota", granted, len(uniqueLinks))
+		uniqueLinks = uniqueLinks[:granted]
+	}
+	if warning := truncated.warning(); warning != nil {
+		info.Warnings = append(info.Warnings, *warning)
+	}
 	info.TotalLinks = len(uniqueLinks)
-	var wg sync.WaitGroup
-
-	for _, link := range uniqueLinks {
-		if isInternalLink(parsedBaseURL, link) {
+	info.Links = make([]LinkResult, len(uniqueLinks))
+	sess.progress.linksFound(len(uniqueLinks))
+	sess.progress.phase(PhaseCheckingLinks)
+	for i, link := range uniqueLinks {
+		internal := opts.Scope.IsInternal(parsedBaseURL, link.URL)
+		if internal {
 			info.InternalLinks++
 		} else {
 			info.ExternalLinks++
 		}
+		info.Links[i] = LinkResult{
+			URL:           link.URL,
+			NormalizedURL: link.NormalizedURL,
+			IsInternal:    internal,
+			AnchorText:    link.AnchorText,
+		}
+	}
+
+	// Check the links with a bounded pool of workers; the shared limiter additionally
+	// caps in-flight requests across all crawls and per host.
+	// Links still unchecked when the crawl has to stop are left out of the result.
+	jobs := make(chan int)
+	checked := make([]bool, len(info.Links))
+	var wg sync.WaitGroup
+	var panics panicCarrier
+	for range min(wc.cfg.LinkCheckWorkers, len(info.Links)) {
 		wg.Add(1)
-		go func(l string) {
+		go func() {
 			defer wg.Done()
-			if isBroken, statusCode := wc.checkLinkStatus(l); isBroken {
-				infoMux.Lock()
-				info.BrokenLinks++
-				info.BrokenLinkDetail = append(info.BrokenLinkDetail, BrokenLinkStatus{URL: l, StatusCode: statusCode})
-				infoMux.Unlock()
+			for i := range jobs {
+				panics.run(func() {
+					result := &info.Links[i]
+					check, ok := wc.checkLinkStatus(result.URL, opts, sess)
+					if !ok {
+						return
+					}
+					checked[i] = true
+					result.StatusCode = check.StatusCode
+					result.Error = check.Error
+					result.RedirectURL = check.RedirectURL
+					result.ResponseTime = check.ResponseTime
+					result.Attempts = check.Attempts
+					result.Blocked = check.Blocked
+					if result.Blocked {
+						infoMux.Lock()
+						info.BlockedLinks++
+						infoMux.Unlock()
+					}
+					if result.IsBroken() {
+						infoMux.Lock()
+						info.BrokenLinks++
+						info.BrokenLinkDetail = append(info.BrokenLinkDetail, BrokenLinkStatus{URL: result.URL, StatusCode: result.StatusCode})
+						infoMux.Unlock()
+					}
+					sess.progress.linkChecked(result.IsBroken())
+				})
 			}
-		}(link)
+		}()
 	}
+	dispatched := 0
+dispatch:
+	for i := range info.Links {
+		select {
+		case jobs <- i:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	info.returnRequests(opts, len(info.Links)-dispatched)
 
 	wg.Wait()
+	panics.raise()
+	if ctx.Err() != nil {
+		found := len(info.Links)
+		keepCheckedLinks(info, checked)
+		return timedOut(ctx, info, opts, start, fmt.Sprintf("after checking %d of %d links", len(info.Links), found))
+	}
+
+	// Site crawls need the sitemaps too, to find pages that are listed but never linked.
+	var sitemaps *sitemapSet
+	if opts.CheckSitemaps || opts.Mode == ModeSite {
+		sess.progress.phase(PhaseCheckingSitemaps)
+		sitemaps = wc.discoverSitemaps(parsedBaseURL, opts, sess)
+	}
+	if opts.CheckSitemaps {
+		info.Sitemap = wc.checkSitemaps(sitemaps, parsedBaseURL, opts, sess, info)
+	}
+	if ctx.Err() != nil {
+		// Sitemaps checked only in part would report the URLs left over as broken.
+		info.Sitemap = nil
+		return timedOut(ctx, info, opts, start, "before its sitemaps were checked")
+	}
+	if opts.Mode == ModeSite {
+		sess.progress.phase(PhaseCrawlingSite)
+		var trapWarning *Warning
+		info.Pages, trapWarning = wc.crawlSite(parsedBaseURL, opts, sess, info)
+		if trapWarning != nil {
+			info.Warnings = append(info.Warnings, *trapWarning)
+		}
+		info.Graph = analyzeGraph(info.Pages, sitemaps.urls, opts.Normalizer)
+		if ctx.Err() != nil {
+			return timedOut(ctx, info, opts, start, fmt.Sprintf("after fetching %d pages of the site", len(info.Pages)))
+		}
+	}
 	info.ProcessingTime = time.Since(start)
 	return info, nil
 }
 
-func (wc *WebCrawler) checkLinkStatus(link string) (bool, int) {
-	wc.cacheMux.RLock()
-	cached, exists := wc.linkCache[link]
-	wc.cacheMux.RUnlock()
-	if exists {
-		return cached.StatusCode >= 400, cached.StatusCode
+// keepCheckedLinks drops the links a crawl didn't get to check from its result. The broken and
+// blocked counts only ever include checked links.
+func keepCheckedLinks(info *PageInfo, checked []bool) {
+	links := info.Links[:0]
+	info.InternalLinks, info.ExternalLinks = 0, 0
+	for i, link := range info.Links {
+		if !checked[i] {
+			continue
+		}
+		links = append(links, link)
+		if link.IsInternal {
+			info.InternalLinks++
+		} else {
+			info.ExternalLinks++
+		}
+	}
+	info.Links = links
+	info.TotalLinks = len(links)
+}
+
+// grantRequests asks the crawl's link budget for n more requests and returns how many of them may
+// be made, counting those in Requests.
+func (info *PageInfo) grantRequests(opts Options, n int) int {
+	granted := n
+	if opts.LinkBudget != nil && n > 0 {
+		granted = min(max(opts.LinkBudget(n), 0), n)
+	}
+	info.Requests += granted
+	return granted
+}
+
+// returnRequests gives n granted requests the crawl didn't get to make back to its link budget.
+func (info *PageInfo) returnRequests(opts Options, n int) {
+	if n <= 0 {
+		return
+	}
+	info.Requests -= n
+	if opts.LinkBudget != nil {
+		opts.LinkBudget(-n)
+	}
+}
+
+// addTruncation adds reason to the TRUNCATED warning of info, creating the warning if needed.
+func (info *PageInfo) addTruncation(reason string) {
+	for i := range info.Warnings {
+		if info.Warnings[i].Code == WarningTruncated {
+			info.Warnings[i].Message += "; " + reason
+			return
+		}
+	}
+	info.Warnings = append(info.Warnings, Warning{Code: WarningTruncated, Message: reason})
+}
+
+// checkLinkStatus checks a link, reporting false when the crawl stopped before the check finished.
+func (wc *WebCrawler) checkLinkStatus(link string, opts Options, sess *session) (LinkCheck, bool) {
+	// Authenticated results depend on the crawl's credentials and those through a dedicated proxy
+	// on the proxy, so they bypass the shared cache.
+	cacheable := !sess.authenticates(link, false) && opts.ProxyURL == nil
+	key := linkCacheKey(link, opts)
+	if cacheable {
+		if cached, ok := wc.linkCache.Get(key); ok {
+			// The crawl made no request of its own for the link.
+			cached.Attempts, cached.ResponseTime = 0, 0
+			return cached, true
+		}
+	}
+	start := time.Now()
+	resp, attempts, err := wc.head(link, opts, sess, false)
+	check := LinkCheck{ResponseTime: time.Since(start), Attempts: attempts}
+	if interrupted(sess.ctx, err) {
+		return check, false
+	}
+	if isBlocked(err) {
+		// Not cached: the verdict depends on the network policy, not on the link.
+		check.Blocked, check.Error = true, err.Error()
+		return check, true
 	}
-	req, _ := http.NewRequest("HEAD", link, nil)
-	req.Header.Set("User-Agent", wc.userAgent)
-	resp, err := wc.httpClient.Do(req)
 	if err != nil {
-		return true, 0
+		check.Error = err.Error()
+	} else {
+		check.StatusCode = resp.StatusCode
+		if final := resp.Request.URL.String(); final != link {
+			check.RedirectURL = final
+		}
 	}
-	defer resp.Body.Close()
-	status := resp.StatusCode
-	wc.cacheMux.Lock()
-	wc.linkCache[link] = BrokenLinkStatus{URL: link, StatusCode: status}
-	wc.cacheMux.Unlock()
-	return status >= 400, status
+	if cacheable {
+		wc.linkCache.Set(key, check)
+	}
+	return check, true
 }
 
-func resolveURL(baseURL *url.URL, href string) string {
-	if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
-		return href
+// linkCacheKey returns the key the check of link with opts is cached under. A site can answer
+// differently depending on the proxy and user agent, so the key includes them.
+func linkCacheKey(link string, opts Options) string {
+	return string(opts.Proxy) + " " + opts.UserAgent + " " + link
+}
+
+// head sends a HEAD request to link, retrying transient failures according to the crawl's policy.
+// It returns the last response with its body already closed and the number of attempts made.
+func (wc *WebCrawler) head(link string, opts Options, sess *session, isTarget bool) (*http.Response, int, error) {
+	client := sess.clientFor(link, isTarget)
+	authenticated := sess.authenticates(link, isTarget)
+	for attempts := 1; ; attempts++ {
+		resp, err := wc.headOnce(sess.ctx, link, opts, client, authenticated)
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode
+		}
+		if interrupted(sess.ctx, err) || !opts.Retry.ShouldRetry(attempts, statusCode, err) {
+			return resp, attempts, err
+		}
+		if err := wait(sess.ctx, opts.Retry.Backoff(attempts)); err != nil {
+			return resp, attempts, err
+		}
 	}
-	resolved, err := baseURL.Parse(href)
+}
+
+// headOnce sends a single HEAD request bounded by the crawl's request timeout.
+func (wc *WebCrawler) headOnce(ctx context.Context, link string, opts Options, client *http.Client, authenticated bool) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", link, nil)
 	if err != nil {
-		return href
+		return nil, err
+	}
+	req.Header.Set("User-Agent", opts.UserAgent)
+	if authenticated {
+		opts.Auth.apply(req.Header)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Close right away: the body holds a limiter slot for the host until it is closed.
+	resp.Body.Close()
+	return resp, nil
+}
+
+// fetch sends a GET request to link, retrying transient failures according to the crawl's policy.
+// It returns the last response, with its body already closed, and up to maxBytes of the body.
+func (wc *WebCrawler) fetch(ctx context.Context, link string, opts Options, client *http.Client, authenticated bool, maxBytes int64) (*http.Response, []byte, error) {
+	for attempts := 1; ; attempts++ {
+		resp, body, err := wc.fetchOnce(ctx, link, opts, client, authenticated, maxBytes)
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode
+		}
+		if interrupted(ctx, err) || !opts.Retry.ShouldRetry(attempts, statusCode, err) {
+			return resp, body, err
+		}
+		if err := wait(ctx, opts.Retry.Backoff(attempts)); err != nil {
+			return resp, body, err
+		}
 	}
-	return resolved.String()
 }
 
-func isInternalLink(baseURL *url.URL, link string) bool {
-	parsed, err := url.Parse(link)
+// fetchOnce sends a single GET request bounded by the crawl's page timeout.
+func (wc *WebCrawler) fetchOnce(ctx context.Context, link string, opts Options, client *http.Client, authenticated bool, maxBytes int64) (*http.Response, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.PageTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
 	if err != nil {
-		return false
+		return nil, nil, err
 	}
-	return parsed.Host == "" || parsed.Host == baseURL.Host
+	req.Header.Set("User-Agent", opts.UserAgent)
+	if authenticated {
+		opts.Auth.apply(req.Header)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
 }
 
-func getUniqueLinks(links []string) []string {
-	seen, unique := make(map[string]struct{}), make([]string, 0)
-	for _, link := range links {
-		if _, ok := seen[link]; !ok {
-			seen[link], unique = struct{}{}, append(unique, link)
+// responseError returns the error colly reported for a response, or nil when the request
+// did get a response and colly only complained about its status code.
+func responseError(r *colly.Response, err error) error {
+	if r.StatusCode != 0 {
+		return nil
+	}
+	return err
+}
+
+// maxHrefLength caps the hrefs worth checking. Servers commonly refuse URLs over 8KB with a
+// 414, and it keeps stored link URLs well within the size of a text column.
+const maxHrefLength = 8 << 10
+
+// unfollowableSchemes are the href schemes that don't point to a document to fetch.
+var unfollowableSchemes = []string{"javascript:", "mailto:", "data:"}
+
+// isFollowableHref reports whether an href points to another document rather than being
+// empty, a fragment on the same page, a script, mail or inline data link, or overly long.
+func isFollowableHref(href string) bool {
+	if href == "" || strings.HasPrefix(href, "#") || len(href) > maxHrefLength {
+		return false
+	}
+	for _, scheme := range unfollowableSchemes {
+		if len(href) >= len(scheme) && strings.EqualFold(href[:len(scheme)], scheme) {
+			return false
 		}
 	}
-	return unique
+	return true
 }
 
-func extractHTMLVersion(html string) string {
-	l := strings.ToLower(html)
-	versions := map[string]string{
-		"<!doctype html>":                  "HTML5",
-		"//dtd html 4.01 strict//en":       "HTML 4.01 Strict",
-		"//dtd html 4.01 transitional//en": "HTML 4.01 Transitional",
-		"//dtd html 4.01 frameset//en":     "HTML 4.01 Frameset",
-		"//dtd html 3.2 final//en":         "HTML 3.2",
-		"//dtd html 2.0//en":               "HTML 2.0",
-		"//dtd xhtml 1.0 strict//en":       "XHTML 1.0 Strict",
-		"//dtd xhtml 1.0 transitional//en": "XHTML 1.0 Transitional",
-		"//dtd xhtml 1.0 frameset//en":     "XHTML 1.0 Frameset",
-		"//dtd xhtml 1.1//en":              "XHTML 1.1",
+func resolveURL(baseURL *url.URL, href string) string {
+	if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+		return href
 	}
-	for p, v := range versions {
-		if strings.Contains(l, p) {
-			return v
+	resolved, err := baseURL.Parse(href)
+	if err != nil {
+		return href
+	}
+	return resolved.String()
+}
+
+// getUniqueLinks removes links that are equal after normalisation, keeping the first
+// occurrence (and its anchor text) of each.
+func getUniqueLinks(links []foundLink, normalizer Normalizer) []foundLink {
+	seen, unique := make(map[string]struct{}), make([]foundLink, 0)
+	for _, link := range links {
+		normalized, err := normalizer.Normalize(link.URL)
+		if err != nil {
+			normalized = link.URL
+		}
+		if _, ok := seen[normalized]; !ok {
+			link.NormalizedURL = normalized
+			seen[normalized], unique = struct{}{}, append(unique, link)
 		}
 	}
-	return "Unknown"
+	return unique
 }
 
 func hasLoginFormHTML(html string) bool {
@@ -0,0 +1,273 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsFollowableHref(t *testing.T) {
+	tests := []struct {
+		href string
+		want bool
+	}{
+		{"/about", true},
+		{"https://example.com/", true},
+		{"", false},
+		{"#top", false},
+		{"javascript:void(0)", false},
+		{"JavaScript:void(0)", false},
+		{"mailto:team@example.com", false},
+		{"data:image/png;base64,iVBORw0KGgo=", false},
+		{"DATA:text/plain,hi", false},
+		{"/search?q=" + strings.Repeat("a", maxHrefLength), false},
+	}
+	for _, tt := range tests {
+		if got := isFollowableHref(tt.href); got != tt.want {
+			t.Errorf("isFollowableHref(%.40q) = %v, want %v", tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlPageChecksOnlyTheLinksItsBudgetGrants(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path != "/" {
+			return
+		}
+		fmt.Fprint(w, `<!DOCTYPE html><html><body>`)
+		for i := range 5 {
+			fmt.Fprintf(w, `<a href="/page-%d">page %d</a>`, i, i)
+		}
+		fmt.Fprint(w, `</body></html>`)
+	}))
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy}, NewMemoryLinkCache(100, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+	opts := wc.DefaultOptions()
+	var asked int
+	opts.LinkBudget = func(n int) int {
+		asked = n
+		return 2
+	}
+
+	info, err := wc.CrawlPage(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("CrawlPage: %v", err)
+	}
+	if asked != 5 {
+		t.Errorf("budget was asked for %d link checks, want 5", asked)
+	}
+	if info.TotalLinks != 2 || len(info.Links) != 2 {
+		t.Errorf("crawl checked %d links (%d in the result), want 2", info.TotalLinks, len(info.Links))
+	}
+	truncated := false
+	for _, w := range info.Warnings {
+		truncated = truncated || w.Code == WarningTruncated
+	}
+	if !truncated {
+		t.Errorf("crawl has no %s warning: %+v", WarningTruncated, info.Warnings)
+	}
+}
+
+func TestCrawlPageChargesSitemapsAndSitePagesToTheBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>http://%[1]s/a</loc></url><url><loc>http://%[1]s/b</loc></url></urlset>`, r.Host)
+		case "/robots.txt":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<!DOCTYPE html><html><body><a href="/a">a</a><a href="/b">b</a></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy}, NewMemoryLinkCache(100, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+
+	tests := []struct {
+		name        string
+		budget      int
+		wantChecked int // Sitemap URLs checked
+		wantPages   int
+	}{
+		{"enough for everything", 100, 2, 3},
+		{"enough for the links and one sitemap URL", 3, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := wc.DefaultOptions()
+			opts.Mode = ModeSite
+			opts.MaxPages = 10
+			opts.MaxDepth = 1
+			opts.CheckSitemaps = true
+			left := tt.budget
+			opts.LinkBudget = func(n int) int {
+				granted := min(n, left)
+				left -= granted
+				return granted
+			}
+
+			info, err := wc.CrawlPage(context.Background(), server.URL, opts)
+			if err != nil {
+				t.Fatalf("CrawlPage: %v", err)
+			}
+			if info.Sitemap == nil || info.Sitemap.Checked != tt.wantChecked {
+				t.Errorf("sitemap report = %+v, want %d URLs checked", info.Sitemap, tt.wantChecked)
+			}
+			if len(info.Pages) != tt.wantPages {
+				t.Errorf("crawl fetched %d site pages, want %d", len(info.Pages), tt.wantPages)
+			}
+			if used := tt.budget - left; info.Requests != used {
+				t.Errorf("Requests = %d, want the %d granted", info.Requests, used)
+			}
+		})
+	}
+}
+
+func TestLinkCacheHitsAreKeyedAndMakeNoAttempts(t *testing.T) {
+	var linkHeads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/link" {
+			if r.Method == http.MethodHead {
+				linkHeads.Add(1)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!DOCTYPE html><html><body><a href="/link">link</a></body></html>`)
+	}))
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy}, NewMemoryLinkCache(100, DefaultCacheTTLs()), NewHostLimiter(LimiterConfig{}))
+
+	tests := []struct {
+		name         string
+		userAgent    string
+		wantHeads    int32 // HEAD requests the link has received after the crawl
+		wantAttempts int
+	}{
+		{"first check", "agent-a", 1, 1},
+		{"cached check", "agent-a", 1, 0},
+		{"other user agent", "agent-b", 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := wc.DefaultOptions()
+			opts.UserAgent = tt.userAgent
+			info, err := wc.CrawlPage(context.Background(), server.URL, opts)
+			if err != nil {
+				t.Fatalf("CrawlPage: %v", err)
+			}
+			if len(info.Links) != 1 {
+				t.Fatalf("crawl found %d links, want 1", len(info.Links))
+			}
+			if got := linkHeads.Load(); got != tt.wantHeads {
+				t.Errorf("link received %d HEAD requests, want %d", got, tt.wantHeads)
+			}
+			if link := info.Links[0]; link.Attempts != tt.wantAttempts || (tt.wantAttempts == 0 && link.ResponseTime != 0) {
+				t.Errorf("link result attempts = %d, response time = %s, want %d attempts", link.Attempts, link.ResponseTime, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestCrawlPageRetriesTheFailedPageFetch(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			return
+		}
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!DOCTYPE html><html><head><title>Back up</title></head><body><a href="/a">a</a></body></html>`)
+	}))
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy}, NewMemoryLinkCache(100, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+	opts := wc.DefaultOptions()
+	opts.Retry.InitialBackoff = time.Millisecond
+	opts.Retry.Jitter = 0
+
+	info, err := wc.CrawlPage(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("CrawlPage: %v", err)
+	}
+	if requests.Load() != 2 || info.Title != "Back up" || info.TotalLinks != 1 {
+		t.Errorf("after %d page requests the crawl found title %q and %d links, want 2 requests, %q and 1 link",
+			requests.Load(), info.Title, info.TotalLinks, "Back up")
+	}
+}
+
+func TestSitePagesRecordTheLimitsTheyHit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<!DOCTYPE html><html><body><a href="/big">big</a></body></html>`)
+		case "/big":
+			fmt.Fprint(w, `<!DOCTYPE html><html><body>`+strings.Repeat("<p>text</p>", 50)+`</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy, Limits: BodyLimits{MaxDOMElements: 20}}, NewMemoryLinkCache(100, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+	opts := wc.DefaultOptions()
+	opts.Mode = ModeSite
+	opts.MaxPages = 10
+	opts.MaxDepth = 1
+
+	info, err := wc.CrawlPage(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("CrawlPage: %v", err)
+	}
+	truncated := make(map[string]string)
+	for _, page := range info.Pages {
+		truncated[page.URL] = page.Truncated
+	}
+	if got := truncated[server.URL+"/big"]; !strings.Contains(got, "more than 20 elements") {
+		t.Errorf("trimmed page has Truncated = %q, want the element limit", got)
+	}
+	if got := truncated[server.URL]; got != "" {
+		t.Errorf("complete page has Truncated = %q, want none", got)
+	}
+	warned := false
+	for _, w := range info.Warnings {
+		warned = warned || w.Code == WarningTruncated && strings.Contains(w.Message, "1 site pages")
+	}
+	if !warned {
+		t.Errorf("crawl doesn't warn about the trimmed site page: %+v", info.Warnings)
+	}
+}
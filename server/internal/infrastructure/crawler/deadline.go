@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WarningTimedOut flags a crawl that ran past its deadline; its result covers what was done by then.
+const WarningTimedOut = "TIMED_OUT"
+
+// ErrTimedOut is returned, along with the partial result if there is one, by a crawl that ran
+// past its deadline.
+var ErrTimedOut = errors.New("crawl timed out")
+
+// wait pauses for d, returning the context's error early if it ends first.
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// interrupted reports whether a request failed because the crawl's context ended rather than
+// because of the target, in which case its outcome says nothing about the URL.
+func interrupted(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil
+}
+
+// stopped returns the error a crawl whose context ended returns: ErrTimedOut when its deadline
+// passed, the context's own error when it was cancelled.
+func stopped(ctx context.Context, opts Options) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimedOut, opts.Timeout)
+	}
+	return ctx.Err()
+}
+
+// timedOut ends a crawl whose context ended with what it got done, described by progress.
+func timedOut(ctx context.Context, info *PageInfo, opts Options, start time.Time, progress string) (*PageInfo, error) {
+	err := stopped(ctx, opts)
+	if errors.Is(err, ErrTimedOut) {
+		info.Warnings = append(info.Warnings, Warning{
+			Code:    WarningTimedOut,
+			Message: fmt.Sprintf("the crawl stopped at its %s deadline %s", opts.Timeout, progress),
+		})
+	}
+	info.ProcessingTime = time.Since(start)
+	return info, err
+}
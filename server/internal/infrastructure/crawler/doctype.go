@@ -0,0 +1,239 @@
+package crawler
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// HTML versions reported when the doctype doesn't name one.
+const (
+	HTMLVersionNone    = "No doctype" // The page has no doctype at all
+	HTMLVersionUnknown = "Unknown"    // The page has a doctype that isn't recognised
+)
+
+// Document modes a doctype puts browsers in, as defined by the HTML standard.
+const (
+	DocumentModeNoQuirks      = "no-quirks"
+	DocumentModeLimitedQuirks = "limited-quirks"
+	DocumentModeQuirks        = "quirks"
+)
+
+// Doctype is the parsed doctype of a page.
+type Doctype struct {
+	Raw      string // The doctype as written, without the surrounding <! and >
+	Name     string
+	PublicID string
+	SystemID string
+	Version  string // e.g. "HTML5", "HTML 4.01 Strict", HTMLVersionNone or HTMLVersionUnknown
+	Mode     string // no-quirks, limited-quirks or quirks
+}
+
+// publicVersions maps lowercased public identifiers to HTML versions.
+var publicVersions = map[string]string{
+	"-//w3c//dtd html 4.01//en":              "HTML 4.01 Strict",
+	"-//w3c//dtd html 4.01 transitional//en": "HTML 4.01 Transitional",
+	"-//w3c//dtd html 4.01 frameset//en":     "HTML 4.01 Frameset",
+	"-//w3c//dtd html 4.0//en":               "HTML 4.0 Strict",
+	"-//w3c//dtd html 4.0 transitional//en":  "HTML 4.0 Transitional",
+	"-//w3c//dtd html 4.0 frameset//en":      "HTML 4.0 Frameset",
+	"-//w3c//dtd html 3.2 final//en":         "HTML 3.2",
+	"-//w3c//dtd html 3.2//en":               "HTML 3.2",
+	"-//ietf//dtd html 2.0//en":              "HTML 2.0",
+	"-//ietf//dtd html//en":                  "HTML 2.0",
+	"-//w3c//dtd xhtml 1.0 strict//en":       "XHTML 1.0 Strict",
+	"-//w3c//dtd xhtml 1.0 transitional//en": "XHTML 1.0 Transitional",
+	"-//w3c//dtd xhtml 1.0 frameset//en":     "XHTML 1.0 Frameset",
+	"-//w3c//dtd xhtml 1.1//en":              "XHTML 1.1",
+	"-//w3c//dtd xhtml basic 1.0//en":        "XHTML Basic 1.0",
+	"-//w3c//dtd xhtml basic 1.1//en":        "XHTML Basic 1.1",
+	"-//w3c//dtd xhtml+rdfa 1.0//en":         "XHTML+RDFa 1.0",
+	"-//w3c//dtd xhtml+rdfa 1.1//en":         "XHTML+RDFa 1.1",
+	"-//wapforum//dtd xhtml mobile 1.0//en":  "XHTML Mobile 1.0",
+	"-//wapforum//dtd xhtml mobile 1.2//en":  "XHTML Mobile 1.2",
+}
+
+// systemVersions maps the file names of well-known DTDs to HTML versions, for SYSTEM-only doctypes.
+var systemVersions = map[string]string{
+	"strict.dtd":              "HTML 4.01 Strict",
+	"loose.dtd":               "HTML 4.01 Transitional",
+	"frameset.dtd":            "HTML 4.01 Frameset",
+	"xhtml1-strict.dtd":       "XHTML 1.0 Strict",
+	"xhtml1-transitional.dtd": "XHTML 1.0 Transitional",
+	"xhtml1-frameset.dtd":     "XHTML 1.0 Frameset",
+	"xhtml11.dtd":             "XHTML 1.1",
+	"xhtml-basic11.dtd":       "XHTML Basic 1.1",
+}
+
+// quirksPublicPrefixes are the public identifier prefixes that trigger quirks mode.
+var quirksPublicPrefixes = []string{
+	"+//silmaril//dtd html pro v0r11 19970101//",
+	"-//as//dtd html 3.0 aswedit + extensions//",
+	"-//advasoft ltd//dtd html 3.0 aswedit + extensions//",
+	"-//ietf//dtd html 2.0 level 1//",
+	"-//ietf//dtd html 2.0 level 2//",
+	"-//ietf//dtd html 2.0 strict level 1//",
+	"-//ietf//dtd html 2.0 strict level 2//",
+	"-//ietf//dtd html 2.0 strict//",
+	"-//ietf//dtd html 2.0//",
+	"-//ietf//dtd html 2.1e//",
+	"-//ietf//dtd html 3.0//",
+	"-//ietf//dtd html 3.2 final//",
+	"-//ietf//dtd html 3.2//",
+	"-//ietf//dtd html 3//",
+	"-//ietf//dtd html level 0//",
+	"-//ietf//dtd html level 1//",
+	"-//ietf//dtd html level 2//",
+	"-//ietf//dtd html level 3//",
+	"-//ietf//dtd html strict level 0//",
+	"-//ietf//dtd html strict level 1//",
+	"-//ietf//dtd html strict level 2//",
+	"-//ietf//dtd html strict level 3//",
+	"-//ietf//dtd html strict//",
+	"-//ietf//dtd html//",
+	"-//metrius//dtd metrius presentational//",
+	"-//microsoft//dtd internet explorer 2.0 html strict//",
+	"-//microsoft//dtd internet explorer 2.0 html//",
+	"-//microsoft//dtd internet explorer 2.0 tables//",
+	"-//microsoft//dtd internet explorer 3.0 html strict//",
+	"-//microsoft//dtd internet explorer 3.0 html//",
+	"-//microsoft//dtd internet explorer 3.0 tables//",
+	"-//netscape comm. corp.//dtd html//",
+	"-//netscape comm. corp.//dtd strict html//",
+	"-//o'reilly and associates//dtd html 2.0//",
+	"-//o'reilly and associates//dtd html extended 1.0//",
+	"-//o'reilly and associates//dtd html extended relaxed 1.0//",
+	"-//sq//dtd html 2.0 hotmetal + extensions//",
+	"-//softquad software//dtd hotmetal pro 6.0::19990601::extensions to html 4.0//",
+	"-//softquad//dtd hotmetal pro 4.0::19971010::extensions to html 4.0//",
+	"-//spyglass//dtd html 2.0 extended//",
+	"-//sun microsystems corp.//dtd hotjava html//",
+	"-//sun microsystems corp.//dtd hotjava strict html//",
+	"-//w3c//dtd html 3 1995-03-24//",
+	"-//w3c//dtd html 3.2 draft//",
+	"-//w3c//dtd html 3.2 final//",
+	"-//w3c//dtd html 3.2//",
+	"-//w3c//dtd html 3.2s draft//",
+	"-//w3c//dtd html 4.0 frameset//",
+	"-//w3c//dtd html 4.0 transitional//",
+	"-//w3c//dtd html experimental 19960712//",
+	"-//w3c//dtd html experimental 970421//",
+	"-//w3c//dtd w3 html//",
+	"-//w3o//dtd w3 html 3.0//",
+	"-//webtechs//dtd mozilla html 2.0//",
+	"-//webtechs//dtd mozilla html//",
+}
+
+// parseDoctype finds the doctype of a page with the HTML tokenizer. Only a doctype that comes
+// before any element or text counts, so one quoted in a comment or code sample is ignored.
+func parseDoctype(body []byte) Doctype {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.CommentToken:
+			continue // Includes <?xml ...?> declarations
+		case html.TextToken:
+			if len(bytes.TrimSpace(z.Text())) == 0 {
+				continue
+			}
+		case html.DoctypeToken:
+			return newDoctype(string(z.Text()))
+		}
+		return Doctype{Version: HTMLVersionNone, Mode: DocumentModeQuirks}
+	}
+}
+
+// newDoctype parses the contents of a doctype token and classifies it.
+func newDoctype(raw string) Doctype {
+	d := Doctype{Raw: strings.TrimSpace(raw)}
+	rest := d.Raw
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		d.Name, rest = rest[:i], strings.TrimSpace(rest[i:])
+	} else {
+		d.Name, rest = rest, ""
+	}
+	d.Name = strings.ToLower(d.Name)
+
+	wellFormed := true
+	// Compare the raw bytes: lowering a cut that splits a rune would change its length.
+	keyword := rest[:min(len(rest), 6)]
+	switch {
+	case strings.EqualFold(keyword, "public"):
+		rest = strings.TrimSpace(rest[len("public"):])
+		if d.PublicID, rest, wellFormed = readQuoted(rest); wellFormed && rest != "" {
+			d.SystemID, _, wellFormed = readQuoted(rest)
+		}
+	case strings.EqualFold(keyword, "system"):
+		d.SystemID, _, wellFormed = readQuoted(strings.TrimSpace(rest[len("system"):]))
+	case rest == "":
+	default:
+		wellFormed = false
+	}
+
+	d.Version = d.version()
+	d.Mode = d.mode(wellFormed)
+	return d
+}
+
+// readQuoted reads a single- or double-quoted string from the start of s.
+func readQuoted(s string) (value, rest string, ok bool) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return "", s, false
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", "", false
+	}
+	return s[1 : end+1], strings.TrimSpace(s[end+2:]), true
+}
+
+// version names the HTML version the doctype declares.
+func (d Doctype) version() string {
+	if d.Name != "html" {
+		return HTMLVersionUnknown
+	}
+	if d.PublicID == "" && (d.SystemID == "" || strings.EqualFold(d.SystemID, "about:legacy-compat")) {
+		return "HTML5"
+	}
+	if v, ok := publicVersions[strings.ToLower(d.PublicID)]; ok {
+		return v
+	}
+	if d.PublicID == "" {
+		dtd := strings.ToLower(d.SystemID[strings.LastIndexByte(d.SystemID, '/')+1:])
+		if v, ok := systemVersions[dtd]; ok {
+			return v
+		}
+	}
+	return HTMLVersionUnknown
+}
+
+// mode determines the document mode the doctype triggers in browsers.
+func (d Doctype) mode(wellFormed bool) string {
+	public := strings.ToLower(d.PublicID)
+	system := strings.ToLower(d.SystemID)
+	if !wellFormed || d.Name != "html" ||
+		public == "-//w3o//dtd w3 html strict 3.0//en//" ||
+		public == "-/w3c/dtd html 4.0 transitional/en" ||
+		public == "html" ||
+		system == "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd" {
+		return DocumentModeQuirks
+	}
+	for _, prefix := range quirksPublicPrefixes {
+		if strings.HasPrefix(public, prefix) {
+			return DocumentModeQuirks
+		}
+	}
+	html401Loose := strings.HasPrefix(public, "-//w3c//dtd html 4.01 frameset//") ||
+		strings.HasPrefix(public, "-//w3c//dtd html 4.01 transitional//")
+	if html401Loose && d.SystemID == "" {
+		return DocumentModeQuirks
+	}
+	if html401Loose ||
+		strings.HasPrefix(public, "-//w3c//dtd xhtml 1.0 frameset//") ||
+		strings.HasPrefix(public, "-//w3c//dtd xhtml 1.0 transitional//") {
+		return DocumentModeLimitedQuirks
+	}
+	return DocumentModeNoQuirks
+}
@@ -0,0 +1,27 @@
+package crawler
+
+import "testing"
+
+func TestParseDoctype(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		version string
+		mode    string
+	}{
+		{"html5", "<!DOCTYPE html><html></html>", "HTML5", DocumentModeNoQuirks},
+		{"no doctype", "<html></html>", HTMLVersionNone, DocumentModeQuirks},
+		{"lowercase keyword", `<!doctype html public "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`, "XHTML 1.0 Strict", DocumentModeNoQuirks},
+		{"system only", `<!DOCTYPE html SYSTEM "about:legacy-compat">`, "HTML5", DocumentModeNoQuirks},
+		{"multibyte rune across the keyword", "<!DOCTYPE html abcdeé>", "HTML5", DocumentModeQuirks},
+		{"multibyte rune after a short keyword", "<!DOCTYPE html éé>", "HTML5", DocumentModeQuirks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := parseDoctype([]byte(tt.body))
+			if d.Version != tt.version || d.Mode != tt.mode {
+				t.Errorf("parseDoctype(%q) = %s/%s, want %s/%s", tt.body, d.Version, d.Mode, tt.version, tt.mode)
+			}
+		})
+	}
+}
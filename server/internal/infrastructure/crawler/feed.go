@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+)
+
+// maxFeedItems caps the items listed in a feed result; all item links are still checked.
+const maxFeedItems = 50
+
+// FeedInfo describes an RSS, Atom or JSON feed.
+type FeedInfo struct {
+	Format    string     `json:"format"` // rss, atom, rdf or json
+	Title     string     `json:"title"`
+	ItemCount int        `json:"item_count"`
+	Items     []FeedItem `json:"items"`
+	Error     string     `json:"error,omitempty"` // Set when the feed couldn't be parsed
+}
+
+// FeedItem is a single entry of a feed.
+type FeedItem struct {
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Published string `json:"published,omitempty"`
+}
+
+// feedDocument maps the elements RSS 2.0, RSS 1.0 (RDF) and Atom feeds use, by local name.
+type feedDocument struct {
+	XMLName xml.Name
+	Title   string      `xml:"title"` // Atom
+	Entries []feedEntry `xml:"entry"` // Atom
+	Items   []feedEntry `xml:"item"`  // RDF
+	Channel struct {
+		Title string      `xml:"title"`
+		Items []feedEntry `xml:"item"`
+	} `xml:"channel"`
+}
+
+type feedEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href  string `xml:"href,attr"`
+		Rel   string `xml:"rel,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"link"`
+	PubDate   string `xml:"pubDate"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Date      string `xml:"date"` // Dublin Core, used by RDF feeds
+}
+
+// jsonFeed maps the fields of a JSON Feed (https://jsonfeed.org).
+type jsonFeed struct {
+	Title string `json:"title"`
+	Items []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		DatePublished string `json:"date_published"`
+	} `json:"items"`
+}
+
+// feedFormat names the feed format of an XML root element, or returns "" if it isn't a feed.
+func feedFormat(root xml.Name) string {
+	switch {
+	case root.Local == "rss":
+		return "rss"
+	case root.Local == "feed" && strings.HasPrefix(root.Space, "http://www.w3.org/2005/Atom"):
+		return "atom"
+	case root.Local == "RDF":
+		return "rdf"
+	}
+	return ""
+}
+
+// analyzeFeed lists the items of a feed and returns their links for checking.
+func analyzeFeed(mediaType string, body []byte) (*FeedInfo, []foundLink) {
+	if mediaType == "application/feed+json" {
+		return analyzeJSONFeed(body)
+	}
+
+	var doc feedDocument
+	d := newXMLDecoder(body)
+	// Feeds are often sloppy: tolerate HTML entities and unclosed tags.
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	if err := d.Decode(&doc); err != nil {
+		return &FeedInfo{Format: "rss", Error: err.Error()}, nil
+	}
+
+	info := &FeedInfo{Format: feedFormat(doc.XMLName)}
+	entries := doc.Entries
+	switch info.Format {
+	case "atom":
+		info.Title = doc.Title
+	case "rdf":
+		info.Title = doc.Channel.Title
+		entries = doc.Items
+	default:
+		info.Format = "rss"
+		info.Title = doc.Channel.Title
+		entries = doc.Channel.Items
+	}
+	info.Title = strings.TrimSpace(info.Title)
+
+	var links []foundLink
+	for _, entry := range entries {
+		item := FeedItem{
+			Title:     strings.TrimSpace(entry.Title),
+			Link:      entry.link(),
+			Published: firstNonEmpty(entry.PubDate, entry.Published, entry.Updated, entry.Date),
+		}
+		info.addItem(item)
+		if item.Link != "" {
+			links = append(links, foundLink{URL: item.Link, AnchorText: item.Title})
+		}
+	}
+	return info, links
+}
+
+// link returns the entry's link: the RSS element text, or Atom's alternate link.
+func (e feedEntry) link() string {
+	for _, l := range e.Links {
+		if l.Href == "" {
+			if v := strings.TrimSpace(l.Value); v != "" {
+				return v
+			}
+			continue
+		}
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func analyzeJSONFeed(body []byte) (*FeedInfo, []foundLink) {
+	var feed jsonFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return &FeedInfo{Format: "json", Error: err.Error()}, nil
+	}
+	info := &FeedInfo{Format: "json", Title: strings.TrimSpace(feed.Title)}
+	var links []foundLink
+	for _, entry := range feed.Items {
+		item := FeedItem{Title: strings.TrimSpace(entry.Title), Link: entry.URL, Published: entry.DatePublished}
+		info.addItem(item)
+		if item.Link != "" {
+			links = append(links, foundLink{URL: item.Link, AnchorText: item.Title})
+		}
+	}
+	return info, links
+}
+
+func (f *FeedInfo) addItem(item FeedItem) {
+	f.ItemCount++
+	if len(f.Items) < maxFeedItems {
+		f.Items = append(f.Items, item)
+	}
+}
@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"math"
+	"net/http"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9 // Total change of the ranks below which iteration stops
+)
+
+// SiteGraph is the internal link graph of a site crawl. Its nodes are the crawled pages, which
+// carry their own metrics; edges between them are listed here, keyed by normalised URL.
+type SiteGraph struct {
+	Edges   []SiteEdge `json:"edges"`
+	Orphans []string   `json:"orphans"` // Sitemap URLs no crawled page links to
+}
+
+// SiteEdge is a link from one crawled page to another.
+type SiteEdge struct {
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	AnchorText string `json:"anchor_text"`
+	Redirect   bool   `json:"redirect,omitempty"` // The source redirects to the target
+}
+
+// analyzeGraph builds the link graph of the crawled pages and fills in their inlink and outlink
+// counts, PageRank and dead-end flag. sitemapURLs are checked for pages nothing links to.
+func analyzeGraph(pages []SitePage, sitemapURLs []sitemapURL, normalizer Normalizer) *SiteGraph {
+	index := make(map[string]int, len(pages))
+	for i, page := range pages {
+		index[page.NormalizedURL] = i
+	}
+
+	graph := &SiteGraph{Edges: make([]SiteEdge, 0), Orphans: make([]string, 0)}
+	linked := make(map[string]struct{})
+	out := make([][]int, len(pages))
+	addEdge := func(source int, target string, edge SiteEdge) {
+		if edge.Source == target {
+			return // Self-links don't count towards the graph
+		}
+		linked[target] = struct{}{}
+		j, ok := index[target]
+		if !ok {
+			return // Beyond the crawl's limits
+		}
+		edge.Target = target
+		graph.Edges = append(graph.Edges, edge)
+		out[source] = append(out[source], j)
+		pages[j].Inlinks++
+	}
+
+	for i := range pages {
+		page := &pages[i]
+		page.Outlinks = len(page.Links)
+		if page.RedirectURL != "" {
+			target, err := normalizer.Normalize(page.RedirectURL)
+			if err != nil {
+				target = page.RedirectURL
+			}
+			addEdge(i, target, SiteEdge{Source: page.NormalizedURL, Redirect: true})
+			page.Outlinks++
+		}
+		for _, link := range page.Links {
+			addEdge(i, link.NormalizedURL, SiteEdge{Source: page.NormalizedURL, AnchorText: link.AnchorText})
+		}
+		_, kind := detectResource(page.ContentType, nil)
+		page.DeadEnd = page.StatusCode == http.StatusOK && kind == ResourceHTML && len(page.Links) == 0
+	}
+
+	for i, rank := range pageRank(out) {
+		pages[i].PageRank = rank
+	}
+
+	// The start page is reached without a link, so it is never an orphan.
+	if len(pages) > 0 {
+		linked[pages[0].NormalizedURL] = struct{}{}
+	}
+	for _, u := range sitemapURLs {
+		if _, ok := linked[u.normalized]; !ok {
+			graph.Orphans = append(graph.Orphans, u.url)
+		}
+	}
+	return graph
+}
+
+// pageRank computes the PageRank of the nodes of a graph given as the targets of each node's
+// edges. Repeated edges between two nodes count once; the rank of nodes without edges is
+// spread evenly over all nodes, so the ranks always sum up to 1.
+func pageRank(out [][]int) []float64 {
+	n := len(out)
+	if n == 0 {
+		return nil
+	}
+	targets := make([][]int, n)
+	for i, edges := range out {
+		seen := make(map[int]struct{}, len(edges))
+		for _, j := range edges {
+			if _, ok := seen[j]; !ok && j != i {
+				seen[j] = struct{}{}
+				targets[i] = append(targets[i], j)
+			}
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for range pageRankIterations {
+		next := make([]float64, n)
+		dangling := 0.0
+		for i, edges := range targets {
+			if len(edges) == 0 {
+				dangling += rank[i]
+				continue
+			}
+			share := rank[i] / float64(len(edges))
+			for _, j := range edges {
+				next[j] += share
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		delta := 0.0
+		for i := range next {
+			next[i] = base + pageRankDamping*next[i]
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	_ "image/gif" // Register decoders for image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// ImageInfo describes an image.
+type ImageInfo struct {
+	Format string `json:"format"` // e.g. png, jpeg, gif, webp, svg
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Error  string `json:"error,omitempty"` // Set when the dimensions couldn't be read
+}
+
+// analyzeImage reads the format and dimensions of an image without decoding its pixels.
+func analyzeImage(mediaType string, body []byte) *ImageInfo {
+	if mediaType == "image/svg+xml" {
+		return analyzeSVG(body)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return &ImageInfo{Format: strings.TrimPrefix(mediaType, "image/"), Error: err.Error()}
+	}
+	return &ImageInfo{Format: format, Width: cfg.Width, Height: cfg.Height}
+}
+
+// analyzeSVG reads the dimensions of an SVG from its width and height, or its viewBox.
+func analyzeSVG(body []byte) *ImageInfo {
+	info := &ImageInfo{Format: "svg"}
+	d := newXMLDecoder(body)
+	d.Strict = false
+	for {
+		token, err := d.Token()
+		if err != nil {
+			info.Error = err.Error()
+			return info
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var viewBox []string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				info.Width = svgLength(attr.Value)
+			case "height":
+				info.Height = svgLength(attr.Value)
+			case "viewBox":
+				viewBox = strings.Fields(strings.ReplaceAll(attr.Value, ",", " "))
+			}
+		}
+		if (info.Width == 0 || info.Height == 0) && len(viewBox) == 4 {
+			info.Width, info.Height = svgLength(viewBox[2]), svgLength(viewBox[3])
+		}
+		return info
+	}
+}
+
+// svgLength parses an absolute SVG length such as "120" or "120px"; relative lengths yield 0.
+func svgLength(value string) int {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int(f)
+}
@@ -0,0 +1,210 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultBackoff is used when a 429/503 response carries no usable Retry-After header.
+const defaultBackoff = 5 * time.Second
+
+// maxTrackedHosts is the number of hosts kept before idle ones are forgotten.
+const maxTrackedHosts = 1000
+
+// LimiterConfig holds the politeness settings shared by every crawl.
+type LimiterConfig struct {
+	MaxInFlight        int           // Maximum concurrent outbound requests across all crawls
+	PerHostQPS         float64       // Maximum requests per second to a single host, 0 disables the rate limit
+	PerHostConcurrency int           // Maximum concurrent requests to a single host
+	MaxBackoff         time.Duration // Upper bound for a Retry-After pause
+}
+
+// hostState tracks the rate limit, concurrency and backoff of a single host.
+type hostState struct {
+	next         time.Time     // Earliest time the next request may start
+	backoffUntil time.Time     // Set when the host answered 429 or 503
+	slots        chan struct{} // Per-host concurrency semaphore
+}
+
+// HostLimiter enforces a global cap on in-flight requests plus a per-host rate,
+// concurrency limit and Retry-After backoff. It is shared by all crawls.
+type HostLimiter struct {
+	cfg      LimiterConfig
+	interval time.Duration
+	global   chan struct{}
+	mu       sync.Mutex
+	hosts    map[string]*hostState
+}
+
+// NewHostLimiter creates a limiter from the given configuration.
+func NewHostLimiter(cfg LimiterConfig) *HostLimiter {
+	l := &HostLimiter{
+		cfg:   cfg,
+		hosts: make(map[string]*hostState),
+	}
+	if cfg.MaxInFlight > 0 {
+		l.global = make(chan struct{}, cfg.MaxInFlight)
+	}
+	if cfg.PerHostQPS > 0 {
+		l.interval = time.Duration(float64(time.Second) / cfg.PerHostQPS)
+	}
+	return l
+}
+
+// Acquire blocks until a request to host may start and returns a function that releases its slots.
+// The global slot is taken last, so that requests waiting on a slow or backed-off host don't hold
+// up the requests to every other host.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	state := l.hostState(host)
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	releaseHost := func() {
+		if state.slots != nil {
+			<-state.slots
+		}
+	}
+
+	// Reserve the next start time for this host, then sleep until it arrives.
+	l.mu.Lock()
+	start := time.Now()
+	if state.next.After(start) {
+		start = state.next
+	}
+	if state.backoffUntil.After(start) {
+		start = state.backoffUntil
+	}
+	state.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			releaseHost()
+			return nil, ctx.Err()
+		}
+	}
+
+	if l.global == nil {
+		return releaseHost, nil
+	}
+	select {
+	case l.global <- struct{}{}:
+	case <-ctx.Done():
+		releaseHost()
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-l.global
+		releaseHost()
+	}, nil
+}
+
+// Backoff pauses all requests to host for the given duration, capped at MaxBackoff.
+func (l *HostLimiter) Backoff(host string, d time.Duration) {
+	if l.cfg.MaxBackoff > 0 && d > l.cfg.MaxBackoff {
+		d = l.cfg.MaxBackoff
+	}
+	state := l.hostState(host)
+	l.mu.Lock()
+	if until := time.Now().Add(d); until.After(state.backoffUntil) {
+		state.backoffUntil = until
+	}
+	l.mu.Unlock()
+}
+
+// hostState returns the state for host, creating it (and forgetting idle hosts) as needed.
+func (l *HostLimiter) hostState(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if state, ok := l.hosts[host]; ok {
+		return state
+	}
+
+	if len(l.hosts) >= maxTrackedHosts {
+		now := time.Now()
+		for h, state := range l.hosts {
+			if state.next.Before(now) && state.backoffUntil.Before(now) && len(state.slots) == 0 {
+				delete(l.hosts, h)
+			}
+		}
+	}
+
+	state := &hostState{}
+	if l.cfg.PerHostConcurrency > 0 {
+		state.slots = make(chan struct{}, l.cfg.PerHostConcurrency)
+	}
+	l.hosts[host] = state
+	return state
+}
+
+// limitedTransport is an http.RoundTripper that routes every request through a HostLimiter
+// and backs off hosts that answer 429 Too Many Requests or 503 Service Unavailable.
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *HostLimiter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := canonicalHostname(req.URL.Host)
+	release, err := t.limiter.Acquire(req.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		t.limiter.Backoff(host, parseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+	// Keep the slots until the body has been consumed, not just until the headers arrive.
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the limiter slots of a request once its response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the underlying body and releases the slots exactly once.
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return defaultBackoff
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultBackoff
+}
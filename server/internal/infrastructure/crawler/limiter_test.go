@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquireDoesNotHoldGlobalSlotWhileHostWaits(t *testing.T) {
+	l := NewHostLimiter(LimiterConfig{MaxInFlight: 1, PerHostConcurrency: 1, MaxBackoff: time.Minute})
+	l.Backoff("slow.example", time.Minute)
+
+	waiting, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Acquire(waiting, "slow.example")
+	time.Sleep(20 * time.Millisecond) // Let it start waiting out the backoff
+
+	ctx, cancelOther := context.WithTimeout(context.Background(), time.Second)
+	defer cancelOther()
+	release, err := l.Acquire(ctx, "fast.example")
+	if err != nil {
+		t.Fatalf("request to another host was held up by a backed-off host: %v", err)
+	}
+	release()
+}
+
+func TestAcquireEnforcesGlobalLimit(t *testing.T) {
+	l := NewHostLimiter(LimiterConfig{MaxInFlight: 1})
+	release, err := l.Acquire(context.Background(), "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "b.example"); err == nil {
+		t.Fatal("a second request got past a global limit of one")
+	}
+
+	release()
+	next, err := l.Acquire(context.Background(), "b.example")
+	if err != nil {
+		t.Fatalf("the freed global slot wasn't reusable: %v", err)
+	}
+	next()
+}
@@ -0,0 +1,199 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// WarningTruncated flags a crawl whose target exceeded one of the BodyLimits.
+// The crawl still completes, but its results only cover what was read.
+const WarningTruncated = "TRUNCATED"
+
+// Warning is a non-fatal problem found while crawling.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BodyLimits caps how much of a response the crawler reads and analyses.
+type BodyLimits struct {
+	MaxBodySize         int64 `json:"max_body_size"`         // Bytes read from the wire
+	MaxDecompressedSize int64 `json:"max_decompressed_size"` // Bytes produced by Content-Encoding decompression
+	MaxLinksPerPage     int   `json:"max_links_per_page"`
+	MaxDOMElements      int   `json:"max_dom_elements"`
+}
+
+// DefaultBodyLimits returns the limits used when none are configured.
+func DefaultBodyLimits() BodyLimits {
+	return BodyLimits{
+		MaxBodySize:         10 << 20,
+		MaxDecompressedSize: 50 << 20,
+		MaxLinksPerPage:     5000,
+		MaxDOMElements:      100000,
+	}
+}
+
+// truncation collects the limits a crawl ran into.
+type truncation struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+type truncationKey struct{}
+
+func withTruncation(ctx context.Context, t *truncation) context.Context {
+	return context.WithValue(ctx, truncationKey{}, t)
+}
+
+func truncationFrom(ctx context.Context) *truncation {
+	t, _ := ctx.Value(truncationKey{}).(*truncation)
+	return t
+}
+
+// record notes that a limit was hit; it is safe to call on a nil truncation.
+func (t *truncation) record(format string, args ...any) {
+	if t == nil {
+		return
+	}
+	reason := fmt.Sprintf(format, args...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range t.reasons {
+		if r == reason {
+			return
+		}
+	}
+	t.reasons = append(t.reasons, reason)
+}
+
+// warning returns the TRUNCATED warning, or nil if no limit was hit.
+func (t *truncation) warning() *Warning {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.reasons) == 0 {
+		return nil
+	}
+	return &Warning{Code: WarningTruncated, Message: strings.Join(t.reasons, "; ")}
+}
+
+// cappedTransport is an http.RoundTripper that caps response bodies. It handles gzip and deflate
+// itself so the decompressed size can be capped too; bodies past a cap end early, and the crawl
+// tracking the request (if any) is told so.
+type cappedTransport struct {
+	base   http.RoundTripper
+	limits BodyLimits
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cappedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := truncationFrom(req.Context())
+	raw := &cappedReader{r: resp.Body, remaining: t.limits.MaxBodySize, onLimit: func() {
+		tracker.record("response body exceeded %d bytes", t.limits.MaxBodySize)
+	}}
+
+	var body io.Reader = raw
+	encoding := strings.ToLower(resp.Header.Get("Content-Encoding"))
+	if req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		encoding = "" // No body to decompress
+	}
+	switch encoding {
+	case "gzip", "x-gzip":
+		body, err = gzip.NewReader(raw)
+	case "deflate":
+		body, err = zlib.NewReader(raw)
+	default:
+		resp.Body = readCloser{Reader: raw, Closer: resp.Body}
+		resp.Uncompressed = true // Keeps colly from gunzipping on its own, without a cap
+		return resp, nil
+	}
+	if errors.Is(err, io.EOF) {
+		body, err = bytes.NewReader(nil), nil // Empty body
+	}
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("invalid %s response body: %w", resp.Header.Get("Content-Encoding"), err)
+	}
+
+	decompressed := &cappedReader{r: body, remaining: t.limits.MaxDecompressedSize, onLimit: func() {
+		tracker.record("decompressed response body exceeded %d bytes", t.limits.MaxDecompressedSize)
+	}}
+	resp.Body = readCloser{Reader: decompressed, Closer: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cappedReader reads at most remaining bytes from r and reports whether there was more.
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	onLimit   func()
+	done      bool
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.remaining <= 0 {
+		// Probe for one more byte to tell a body of exactly the limit from a longer one.
+		var probe [1]byte
+		if n, _ := c.r.Read(probe[:]); n > 0 {
+			c.onLimit()
+		}
+		c.done = true
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// capDOMElements cuts an HTML body off before the element that exceeds max, so the DOM
+// colly builds stays bounded. It returns the body unchanged when it is within the limit.
+func capDOMElements(body []byte, max int, tracker *truncation) []byte {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	offset, elements := 0, 0
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			return body
+		}
+		if tokenType == html.StartTagToken || tokenType == html.SelfClosingTagToken {
+			if elements++; elements > max {
+				tracker.record("page has more than %d elements", max)
+				return body[:offset]
+			}
+		}
+		offset += len(z.Raw())
+	}
+}
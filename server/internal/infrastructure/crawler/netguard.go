@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedDestination is returned (wrapped) when a request would connect to an address
+// the network policy forbids. Use errors.Is to detect it.
+var ErrBlockedDestination = errors.New("destination blocked by network policy")
+
+// blockedPrefixes are never dialled unless explicitly allowed: loopback, private, link-local
+// (which includes the 169.254.169.254 cloud metadata endpoint), CGNAT, multicast and reserved ranges.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// NetworkPolicy decides which IP addresses the crawler may connect to.
+// Deny entries always win; Allow entries punch holes into the built-in blocked ranges.
+type NetworkPolicy struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// ParseNetworkPolicy builds a policy from CIDR strings such as "10.1.0.0/16" or single IPs.
+func ParseNetworkPolicy(allow, deny []string) (NetworkPolicy, error) {
+	var policy NetworkPolicy
+	var err error
+	if policy.Allow, err = ParsePrefixes(allow); err != nil {
+		return policy, err
+	}
+	if policy.Deny, err = ParsePrefixes(deny); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// ParsePrefixes parses IP addresses and CIDRs such as "10.1.0.0/16", the format of every IP list
+// in the configuration. Single IPs become one-address prefixes; blank entries are skipped.
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP %q: %w", v, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", v, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Allows reports whether the crawler may connect to addr.
+func (p NetworkPolicy) Allows(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if containsAddr(p.Deny, addr) {
+		return false
+	}
+	if containsAddr(p.Allow, addr) {
+		return true
+	}
+	return !containsAddr(blockedPrefixes, addr)
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// control runs after DNS resolution and right before connecting, so it sees the exact IP
+// being dialled. This covers every redirect hop and defeats DNS rebinding.
+func (p NetworkPolicy) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unparseable address %s", ErrBlockedDestination, address)
+	}
+	if !p.Allows(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedDestination, addrPort.Addr())
+	}
+	return nil
+}
+
+// newGuardedTransport returns an HTTP transport whose dialer enforces the network policy.
+func newGuardedTransport(policy NetworkPolicy) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   policy.control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// An environment proxy would be dialled instead of the target and bypass the guard.
+	transport.Proxy = nil
+	return transport
+}
+
+// isBlocked reports whether err was caused by the network policy.
+func isBlocked(err error) bool {
+	return errors.Is(err, ErrBlockedDestination)
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// TrailingSlashPolicy controls how the normaliser treats a trailing slash on a path.
+type TrailingSlashPolicy string
+
+const (
+	// TrailingSlashStrip removes the trailing slash from every path except the root, so /a and /a/ are equal.
+	TrailingSlashStrip TrailingSlashPolicy = "strip"
+	// TrailingSlashAdd appends a trailing slash to paths whose last segment doesn't look like a file.
+	TrailingSlashAdd TrailingSlashPolicy = "add"
+	// TrailingSlashKeep leaves the path as it was written.
+	TrailingSlashKeep TrailingSlashPolicy = "keep"
+)
+
+// trackingParams are query parameters that never change the content of a page.
+var trackingParams = map[string]struct{}{
+	"gclid":   {},
+	"dclid":   {},
+	"fbclid":  {},
+	"msclkid": {},
+	"yclid":   {},
+	"mc_cid":  {},
+	"mc_eid":  {},
+	"_ga":     {},
+	"_gl":     {},
+	"igshid":  {},
+}
+
+// Normalizer turns URLs into a canonical form so equivalent links compare equal.
+type Normalizer struct {
+	TrailingSlash TrailingSlashPolicy
+}
+
+// Normalize returns the canonical form of rawURL. It lowercases the scheme and host,
+// converts IDNs to punycode, drops trailing dots, default ports, fragments and tracking
+// parameters, resolves dot segments, sorts the query and applies the trailing slash policy.
+func (n Normalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Fragment, u.RawFragment = "", ""
+	u.User = nil
+
+	if u.Host != "" {
+		u.Host = normalizeHost(u.Scheme, u.Host)
+	}
+
+	u.Path = n.normalizePath(u.Path)
+	u.RawPath = ""
+	u.RawQuery = normalizeQuery(u.Query())
+
+	return u.String(), nil
+}
+
+// normalizeHost lowercases the host, converts it to ASCII and strips the default port and trailing dot.
+func normalizeHost(scheme, host string) string {
+	hostname, port := host, ""
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		hostname, port = h, p
+	}
+
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if ascii, err := idna.Lookup.ToASCII(hostname); err == nil {
+		hostname = ascii
+	}
+
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port == "" {
+		if strings.Contains(hostname, ":") {
+			return "[" + hostname + "]" // IPv6 literal
+		}
+		return hostname
+	}
+	return net.JoinHostPort(hostname, port)
+}
+
+// normalizePath resolves dot segments and applies the trailing slash policy.
+func (n Normalizer) normalizePath(p string) string {
+	if p == "" || p == "/" {
+		return "/"
+	}
+
+	hadSlash := strings.HasSuffix(p, "/")
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" {
+		return cleaned
+	}
+
+	switch n.TrailingSlash {
+	case TrailingSlashKeep:
+		if hadSlash {
+			cleaned += "/"
+		}
+	case TrailingSlashAdd:
+		if !strings.Contains(path.Base(cleaned), ".") {
+			cleaned += "/"
+		}
+	}
+	return cleaned
+}
+
+// normalizeQuery removes tracking parameters and encodes the rest sorted by key.
+func normalizeQuery(values url.Values) string {
+	for key := range values {
+		lower := strings.ToLower(key)
+		if _, ok := trackingParams[lower]; ok || strings.HasPrefix(lower, "utm_") {
+			values.Del(key)
+		}
+	}
+	return values.Encode()
+}
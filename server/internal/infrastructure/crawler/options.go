@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"net/url"
+	"time"
+)
+
+// DefaultUserAgent identifies the crawler honestly when no other user agent is configured.
+const DefaultUserAgent = "SykellCrawler/1.0 (+https://github.com/diabahmed/sykell-crawler)"
+
+// UserAgentPresets are the browser and bot user agents a crawl can select by name.
+var UserAgentPresets = map[string]string{
+	"chrome":    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+	"firefox":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:140.0) Gecko/20100101 Firefox/140.0",
+	"safari":    "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+	"mobile":    "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+	"googlebot": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+}
+
+// Config holds the crawler-wide settings; per-crawl settings live in Options.
+type Config struct {
+	UserAgent        string        // Default user agent
+	RequestTimeout   time.Duration // Default timeout of a single link check or HEAD request
+	PageTimeout      time.Duration // Default timeout of the page fetch
+	Timeout          time.Duration // Default wall-clock deadline of a whole crawl; zero means none
+	Delay            time.Duration // Default delay between requests made by the page collector
+	Parallelism      int           // Concurrent requests of the page collector
+	LinkCheckWorkers int           // Links a single crawl checks concurrently
+	Retry            RetryPolicy   // Default retry policy for crawls that don't override it
+	Network          NetworkPolicy // Addresses the crawler may connect to
+	Proxies          []*url.URL    // Proxy pool used round-robin by crawls in pool mode
+	ProxyMode        ProxyMode     // Default proxy mode; pool when proxies are configured, direct otherwise
+	Limits           BodyLimits    // Caps on what is read and analysed of a page
+	SiteMaxPages     int           // Default page budget of site crawls
+	SiteMaxDepth     int           // Default number of links site crawls follow from the target
+	Traps            TrapLimits    // Spider trap heuristics of site crawls
+}
+
+// Options holds the per-crawl settings that influence how a page is fetched and analysed.
+type Options struct {
+	UserAgent      string
+	RequestTimeout time.Duration
+	PageTimeout    time.Duration
+	Timeout        time.Duration // Deadline of the whole crawl, after which it stops with a partial result
+	Delay          time.Duration
+	Scope          Scope
+	Normalizer     Normalizer
+	Retry          RetryPolicy
+	Auth           *Auth          // Credentials for the target site, nil for anonymous crawls
+	Proxy          ProxyMode      // Ignored when ProxyURL is set
+	ProxyURL       *url.URL       // Dedicated proxy for this crawl
+	CheckSitemaps  bool           // Discover and validate the target's sitemaps and check their URLs
+	Mode           string         // ModePage or ModeSite
+	MaxPages       int            // Pages fetched by a site crawl, the target included
+	MaxDepth       int            // Links a site crawl follows from the target
+	Progress       ProgressFunc   // Receives the crawl's progress as it runs; nil to not report it
+	LinkBudget     LinkBudgetFunc // Grants the crawl its requests; nil to make all of them
+}
+
+// LinkBudgetFunc is asked for the n requests a crawl is about to make to check links or sitemap URLs
+// or to fetch site pages, and returns how many of them may go ahead. Those beyond are left out of
+// the result with a TRUNCATED warning. A negative n gives back -n requests granted earlier that the
+// crawl didn't get to make.
+type LinkBudgetFunc func(n int) int
+
+// DefaultOptions returns the options used when a crawl doesn't override any.
+func (wc *WebCrawler) DefaultOptions() Options {
+	return Options{
+		UserAgent:      wc.cfg.UserAgent,
+		RequestTimeout: wc.cfg.RequestTimeout,
+		PageTimeout:    wc.cfg.PageTimeout,
+		Timeout:        wc.cfg.Timeout,
+		Delay:          wc.cfg.Delay,
+		Scope:          Scope{Mode: ScopeHost},
+		Normalizer:     Normalizer{TrailingSlash: TrailingSlashStrip},
+		Retry:          wc.cfg.Retry,
+		Proxy:          wc.cfg.ProxyMode,
+		Mode:           ModePage,
+		MaxPages:       wc.cfg.SiteMaxPages,
+		MaxDepth:       wc.cfg.SiteMaxDepth,
+	}
+}
+
+// Settings is a JSON-friendly snapshot of the settings a crawl actually ran with.
+type Settings struct {
+	UserAgent        string        `json:"user_agent"`
+	RequestTimeoutMs int64         `json:"request_timeout_ms"`
+	PageTimeoutMs    int64         `json:"page_timeout_ms"`
+	TimeoutMs        int64         `json:"timeout_ms,omitempty"`
+	DelayMs          int64         `json:"delay_ms"`
+	Parallelism      int           `json:"parallelism"`
+	LinkCheckWorkers int           `json:"link_check_workers"`
+	Scope            string        `json:"scope"`
+	AllowedHosts     []string      `json:"allowed_hosts,omitempty"`
+	TrailingSlash    string        `json:"trailing_slash"`
+	Retry            RetrySettings `json:"retry"`
+	Authenticated    bool          `json:"authenticated"` // Credentials themselves are never part of the snapshot
+	Proxy            string        `json:"proxy"`         // direct, pool or the crawl's own proxy without credentials
+	Limits           BodyLimits    `json:"limits"`
+	CheckSitemaps    bool          `json:"check_sitemaps"`
+	Mode             string        `json:"mode"`
+	MaxPages         int           `json:"max_pages,omitempty"` // Site crawls only
+	MaxDepth         int           `json:"max_depth,omitempty"`
+	Traps            *TrapLimits   `json:"traps,omitempty"`
+}
+
+// RetrySettings is the JSON-friendly form of a RetryPolicy.
+type RetrySettings struct {
+	MaxAttempts      int      `json:"max_attempts"`
+	InitialBackoffMs int64    `json:"initial_backoff_ms"`
+	MaxBackoffMs     int64    `json:"max_backoff_ms"`
+	Multiplier       float64  `json:"multiplier"`
+	Jitter           float64  `json:"jitter"`
+	StatusCodes      []int    `json:"status_codes"`
+	ErrorClasses     []string `json:"error_classes"`
+}
+
+// EffectiveSettings returns the snapshot of the settings a crawl with opts runs with.
+func (wc *WebCrawler) EffectiveSettings(opts Options) Settings {
+	settings := Settings{
+		UserAgent:        opts.UserAgent,
+		RequestTimeoutMs: opts.RequestTimeout.Milliseconds(),
+		PageTimeoutMs:    opts.PageTimeout.Milliseconds(),
+		TimeoutMs:        opts.Timeout.Milliseconds(),
+		DelayMs:          opts.Delay.Milliseconds(),
+		Parallelism:      wc.cfg.Parallelism,
+		LinkCheckWorkers: wc.cfg.LinkCheckWorkers,
+		Scope:            string(opts.Scope.Mode),
+		AllowedHosts:     opts.Scope.Allowlist,
+		TrailingSlash:    string(opts.Normalizer.TrailingSlash),
+		Retry: RetrySettings{
+			MaxAttempts:      opts.Retry.MaxAttempts,
+			InitialBackoffMs: opts.Retry.InitialBackoff.Milliseconds(),
+			MaxBackoffMs:     opts.Retry.MaxBackoff.Milliseconds(),
+			Multiplier:       opts.Retry.Multiplier,
+			Jitter:           opts.Retry.Jitter,
+			StatusCodes:      opts.Retry.RetryableStatusCodes,
+			ErrorClasses:     opts.Retry.RetryableErrors,
+		},
+		Authenticated: opts.Auth != nil,
+		Proxy:         proxySetting(opts),
+		Limits:        wc.cfg.Limits,
+		CheckSitemaps: opts.CheckSitemaps,
+		Mode:          opts.Mode,
+	}
+	if opts.Mode == ModeSite {
+		settings.MaxPages = opts.MaxPages
+		settings.MaxDepth = opts.MaxDepth
+		settings.Traps = &wc.cfg.Traps
+	}
+	return settings
+}
+
+// proxySetting describes the proxy a crawl with opts uses, without its credentials.
+func proxySetting(opts Options) string {
+	if opts.ProxyURL != nil {
+		return redactProxy(opts.ProxyURL)
+	}
+	return string(opts.Proxy)
+}
@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// panicCarrier carries a panic out of the worker goroutines of a crawl, so that it can be raised
+// again on the goroutine of the crawl, where the caller of CrawlPage can recover it. A panic on a
+// worker would otherwise take down the whole process.
+type panicCarrier struct {
+	mu    sync.Mutex
+	value any // The first panic, with the stack of the worker it happened on
+}
+
+// run calls job and keeps a panic in it for raise. The worker carries on with its next job, so
+// that the jobs still being dispatched don't wait for workers that are gone.
+func (p *panicCarrier) run(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if p.value == nil {
+				p.value = fmt.Sprintf("%v\n\n%s", r, debug.Stack())
+			}
+		}
+	}()
+	job()
+}
+
+// raise panics with the first panic kept by run, if any. Call it once the workers are done.
+func (p *panicCarrier) raise() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.value != nil {
+		panic(p.value)
+	}
+}
@@ -0,0 +1,37 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPanicCarrierKeepsWorkerRunningAndRaisesFirstPanic(t *testing.T) {
+	var panics panicCarrier
+	ran := 0
+	for _, job := range []func(){
+		func() { ran++ },
+		func() { panic("hostile page") },
+		func() { panic("another page") },
+		func() { ran++ },
+	} {
+		panics.run(job)
+	}
+	if ran != 2 {
+		t.Fatalf("%d jobs ran, want the 2 that didn't panic", ran)
+	}
+
+	defer func() {
+		r, _ := recover().(string)
+		if !strings.HasPrefix(r, "hostile page\n") {
+			t.Fatalf("raise panicked with %q, want the first panic", r)
+		}
+	}()
+	panics.raise()
+	t.Fatal("raise didn't panic")
+}
+
+func TestPanicCarrierRaisesNothingWithoutPanic(t *testing.T) {
+	var panics panicCarrier
+	panics.run(func() {})
+	panics.raise()
+}
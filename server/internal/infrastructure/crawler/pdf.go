@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfVersionPattern matches the version in a PDF header such as "%PDF-1.7".
+var pdfVersionPattern = regexp.MustCompile(`%PDF-(\d\.\d)`)
+
+// PDFInfo describes a PDF document.
+type PDFInfo struct {
+	Version      string `json:"version"`
+	Pages        int    `json:"pages"`
+	Title        string `json:"title,omitempty"`
+	Author       string `json:"author,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	Keywords     string `json:"keywords,omitempty"`
+	Creator      string `json:"creator,omitempty"`
+	Producer     string `json:"producer,omitempty"`
+	CreationDate string `json:"creation_date,omitempty"`
+	ModDate      string `json:"mod_date,omitempty"`
+	Error        string `json:"error,omitempty"` // Set when the document could only be partially read
+}
+
+// analyzePDF reads the metadata of a PDF and the URIs of its link annotations.
+func analyzePDF(body []byte) (info *PDFInfo, links []foundLink) {
+	info = &PDFInfo{}
+	if m := pdfVersionPattern.FindSubmatch(body[:min(len(body), 1024)]); m != nil {
+		info.Version = string(m[1])
+	}
+
+	// The PDF reader panics on some malformed documents; keep what was read until then.
+	defer func() {
+		if r := recover(); r != nil {
+			info.Error = fmt.Sprintf("malformed PDF: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		info.Error = err.Error()
+		return info, nil
+	}
+
+	meta := r.Trailer().Key("Info")
+	info.Title = strings.TrimSpace(meta.Key("Title").Text())
+	info.Author = strings.TrimSpace(meta.Key("Author").Text())
+	info.Subject = strings.TrimSpace(meta.Key("Subject").Text())
+	info.Keywords = strings.TrimSpace(meta.Key("Keywords").Text())
+	info.Creator = strings.TrimSpace(meta.Key("Creator").Text())
+	info.Producer = strings.TrimSpace(meta.Key("Producer").Text())
+	info.CreationDate = parsePDFDate(meta.Key("CreationDate").Text())
+	info.ModDate = parsePDFDate(meta.Key("ModDate").Text())
+
+	info.Pages = r.NumPage()
+	for i := 1; i <= info.Pages; i++ {
+		annots := r.Page(i).V.Key("Annots")
+		for j := 0; j < annots.Len(); j++ {
+			annot := annots.Index(j)
+			if annot.Key("Subtype").Name() != "Link" {
+				continue
+			}
+			if uri := strings.TrimSpace(annot.Key("A").Key("URI").RawString()); uri != "" {
+				links = append(links, foundLink{URL: uri})
+			}
+		}
+	}
+	return info, links
+}
+
+// parsePDFDate converts a PDF date such as "D:20240131120000+01'00'" to RFC 3339.
+// Dates that can't be parsed are returned as written.
+func parsePDFDate(value string) string {
+	s := strings.TrimPrefix(strings.TrimSpace(value), "D:")
+	digits, zone := s, ""
+	if i := strings.IndexAny(s, "Z+-"); i >= 0 {
+		digits, zone = s[:i], s[i:]
+	}
+	if len(digits) < 4 {
+		return value
+	}
+	// Missing trailing fields default to the start of the period.
+	digits += "0101000000"[min(10, len(digits)-4):]
+	t, err := time.Parse("20060102150405", digits[:14])
+	if err != nil {
+		return value
+	}
+	if zone = strings.ReplaceAll(zone, "'", ""); len(zone) == 5 {
+		if offset, err := time.Parse("-0700", zone); err == nil {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, offset.Location())
+		}
+	}
+	return t.Format(time.RFC3339)
+}
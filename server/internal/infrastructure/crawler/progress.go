@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// Phases a crawl goes through, reported in its progress.
+const (
+	PhaseFetching         = "fetching"          // Reaching and downloading the target
+	PhaseParsing          = "parsing"           // Analysing the target and extracting its links
+	PhaseCheckingLinks    = "checking_links"    // Checking the links found on the target
+	PhaseCheckingSitemaps = "checking_sitemaps" // Reading the sitemaps and checking their URLs
+	PhaseCrawlingSite     = "crawling_site"     // Fetching the pages of a site crawl
+)
+
+// Progress is a snapshot of how far a running crawl has got.
+type Progress struct {
+	Phase        string
+	LinksChecked int
+	LinksTotal   int
+	BrokenLinks  int // Among the links checked so far
+	PagesVisited int // Pages fetched by a site crawl, the target included
+	Elapsed      time.Duration
+	ETA          time.Duration // Estimated time left in the current phase, zero when unknown
+}
+
+// ProgressFunc receives the progress of a crawl whenever it changes. It is called from the
+// crawl's workers, one call at a time, and should return quickly.
+type ProgressFunc func(Progress)
+
+// progressTracker keeps the progress of a single crawl and reports every change of it.
+// All of its methods do nothing when there is no one to report to.
+type progressTracker struct {
+	mu         sync.Mutex
+	report     ProgressFunc
+	start      time.Time
+	phaseStart time.Time
+	progress   Progress
+}
+
+func newProgressTracker(report ProgressFunc) *progressTracker {
+	now := time.Now()
+	return &progressTracker{report: report, start: now, phaseStart: now}
+}
+
+// phase moves the crawl on to the named phase.
+func (t *progressTracker) phase(name string) {
+	t.update(func(p *Progress) {
+		p.Phase = name
+		t.phaseStart = time.Now()
+	})
+}
+
+// linksFound sets the number of links the crawl is going to check.
+func (t *progressTracker) linksFound(total int) {
+	t.update(func(p *Progress) { p.LinksTotal = total })
+}
+
+// linkChecked counts a checked link.
+func (t *progressTracker) linkChecked(broken bool) {
+	t.update(func(p *Progress) {
+		p.LinksChecked++
+		if broken {
+			p.BrokenLinks++
+		}
+	})
+}
+
+// pageVisited counts a page fetched by a site crawl.
+func (t *progressTracker) pageVisited() {
+	t.update(func(p *Progress) { p.PagesVisited++ })
+}
+
+// update applies change to the progress and reports the result.
+func (t *progressTracker) update(change func(*Progress)) {
+	if t.report == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	change(&t.progress)
+
+	now := time.Now()
+	t.progress.Elapsed = now.Sub(t.start)
+	t.progress.ETA = 0
+	// Only link checks have a known amount of work; extrapolate from the rate so far.
+	if p := t.progress; p.Phase == PhaseCheckingLinks && p.LinksChecked > 0 && p.LinksChecked < p.LinksTotal {
+		perLink := now.Sub(t.phaseStart) / time.Duration(p.LinksChecked)
+		t.progress.ETA = perLink * time.Duration(p.LinksTotal-p.LinksChecked)
+	}
+	t.report(t.progress)
+}
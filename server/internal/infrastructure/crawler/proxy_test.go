@@ -0,0 +1,32 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestCheckProxy(t *testing.T) {
+	dedicated, _ := url.Parse("http://proxy.example:3128")
+	pool, _ := url.Parse("http://pool.example:3128")
+
+	tests := []struct {
+		name    string
+		proxies []*url.URL
+		opts    Options
+		wantErr error
+	}{
+		{"direct without proxies", nil, Options{Proxy: ProxyDirect}, nil},
+		{"pool without proxies", nil, Options{Proxy: ProxyPool}, ErrNoProxies},
+		{"pool with proxies", []*url.URL{pool}, Options{Proxy: ProxyPool}, nil},
+		{"dedicated proxy without a pool", nil, Options{Proxy: ProxyPool, ProxyURL: dedicated}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := NewWebCrawler(Config{Proxies: tt.proxies}, NewMemoryLinkCache(10, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+			if err := wc.CheckProxy(tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckProxy() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
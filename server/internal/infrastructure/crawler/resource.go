@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Kinds of resource a crawl target can be.
+const (
+	ResourceHTML  = "html"
+	ResourcePDF   = "pdf"
+	ResourceFeed  = "feed"
+	ResourceXML   = "xml"
+	ResourceImage = "image"
+	ResourceText  = "text"
+	ResourceOther = "other"
+)
+
+// ResourceInfo is the type-specific analysis of a target that isn't an HTML page.
+// Only the field matching Kind is set.
+type ResourceInfo struct {
+	Kind  string     `json:"kind"`
+	PDF   *PDFInfo   `json:"pdf,omitempty"`
+	Feed  *FeedInfo  `json:"feed,omitempty"`
+	XML   *XMLInfo   `json:"xml,omitempty"`
+	Image *ImageInfo `json:"image,omitempty"`
+	Text  *TextInfo  `json:"text,omitempty"`
+}
+
+// XMLInfo describes a generic XML document.
+type XMLInfo struct {
+	WellFormed  bool   `json:"well_formed"`
+	Error       string `json:"error,omitempty"` // First syntax error, with its line
+	RootElement string `json:"root_element"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// TextInfo describes a plain text document.
+type TextInfo struct {
+	Lines      int `json:"lines"`
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+}
+
+// textURLPattern finds absolute URLs in plain text.
+var textURLPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// detectResource determines the media type and kind of a response. The Content-Type header
+// is trusted unless it is missing or generic, in which case the body is sniffed.
+func detectResource(contentType string, body []byte) (mediaType, kind string) {
+	mediaType, _, _ = mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		mediaType = sniffMediaType(body)
+	}
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return mediaType, ResourceHTML
+	case mediaType == "application/pdf":
+		return mediaType, ResourcePDF
+	case mediaType == "application/rss+xml" || mediaType == "application/atom+xml" || mediaType == "application/feed+json":
+		return mediaType, ResourceFeed
+	case strings.HasPrefix(mediaType, "image/"):
+		return mediaType, ResourceImage
+	case mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml"):
+		// Many feeds are served as plain XML.
+		if root, err := xmlRoot(body); err == nil && feedFormat(root) != "" {
+			return mediaType, ResourceFeed
+		}
+		return mediaType, ResourceXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return mediaType, ResourceText
+	}
+	return mediaType, ResourceOther
+}
+
+// sniffMediaType guesses the media type of a body served without a usable Content-Type.
+func sniffMediaType(body []byte) string {
+	if bytes.HasPrefix(body, []byte("%PDF-")) {
+		return "application/pdf"
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	return mediaType
+}
+
+// analyzeResource produces the type-specific result of a non-HTML target, its title if it has
+// one and the links it contains, resolved against base.
+func analyzeResource(kind, mediaType string, body []byte, base *url.URL) (*ResourceInfo, string, []foundLink) {
+	info := &ResourceInfo{Kind: kind}
+	var title string
+	var links []foundLink
+	switch kind {
+	case ResourcePDF:
+		info.PDF, links = analyzePDF(body)
+		title = info.PDF.Title
+	case ResourceFeed:
+		info.Feed, links = analyzeFeed(mediaType, body)
+		title = info.Feed.Title
+	case ResourceXML:
+		info.XML = analyzeXML(body)
+	case ResourceImage:
+		info.Image = analyzeImage(mediaType, body)
+	case ResourceText:
+		info.Text, links = analyzeText(body)
+	}
+
+	// Like hrefs, documents link to mail addresses, scripts and inline data, which aren't checked.
+	followable := links[:0]
+	for _, l := range links {
+		if isFollowableHref(l.URL) {
+			l.URL = resolveURL(base, l.URL)
+			followable = append(followable, l)
+		}
+	}
+	return info, title, followable
+}
+
+// analyzeXML checks that a document is well-formed XML and reports its root element.
+func analyzeXML(body []byte) *XMLInfo {
+	info := &XMLInfo{WellFormed: true}
+	d := newXMLDecoder(body)
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			info.WellFormed = false
+			info.Error = err.Error()
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok && info.RootElement == "" {
+			info.RootElement = start.Name.Local
+			info.Namespace = start.Name.Space
+		}
+	}
+	if info.WellFormed && info.RootElement == "" {
+		info.WellFormed = false
+		info.Error = "document has no root element"
+	}
+	return info
+}
+
+// xmlRoot returns the name of the root element of an XML document.
+func xmlRoot(body []byte) (xml.Name, error) {
+	d := newXMLDecoder(body)
+	d.Strict = false
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// newXMLDecoder returns a decoder that honours the document's declared encoding.
+func newXMLDecoder(body []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.CharsetReader = charset.NewReaderLabel
+	return d
+}
+
+// analyzeText counts the lines and words of a text document and collects the URLs it mentions.
+func analyzeText(body []byte) (*TextInfo, []foundLink) {
+	text := string(body)
+	info := &TextInfo{
+		Lines:      strings.Count(text, "\n"),
+		Words:      len(strings.Fields(text)),
+		Characters: utf8.RuneCountInString(text),
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		info.Lines++
+	}
+
+	var links []foundLink
+	for _, match := range textURLPattern.FindAllString(text, -1) {
+		links = append(links, foundLink{URL: strings.TrimRight(match, ".,;:!?)]}")})
+	}
+	return info, links
+}
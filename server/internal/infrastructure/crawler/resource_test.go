@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeResourceSkipsUnfollowableLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/feed.xml")
+	feed := `<rss version="2.0"><channel><title>Feed</title>
+		<item><title>Post</title><link>/post</link></item>
+		<item><title>Mail</title><link>mailto:team@example.com</link></item>
+		<item><title>Script</title><link>javascript:void(0)</link></item>
+		<item><title>Inline</title><link>data:text/plain,hi</link></item>
+		<item><title>Other</title><link>https://other.example/</link></item>
+	</channel></rss>`
+
+	_, _, links := analyzeResource(ResourceFeed, "application/rss+xml", []byte(feed), base)
+	var got []string
+	for _, l := range links {
+		got = append(got, l.URL)
+	}
+	want := []string{"https://example.com/post", "https://other.example/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("analyzeResource() links = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Error classes a RetryPolicy can retry on.
+const (
+	ErrorClassTimeout    = "timeout"    // Request or dial timeouts
+	ErrorClassConnection = "connection" // Refused or reset connections and truncated responses
+	ErrorClassDNS        = "dns"        // Temporary DNS failures; unknown hosts are never retried
+)
+
+// RetryPolicy decides whether and when a failed request is attempted again.
+type RetryPolicy struct {
+	MaxAttempts          int           // Total attempts including the first one
+	InitialBackoff       time.Duration // Pause before the second attempt
+	MaxBackoff           time.Duration // Upper bound for a single pause
+	Multiplier           float64       // Growth factor of the pause per attempt
+	Jitter               float64       // Random spread of each pause as a fraction, e.g. 0.2 = ±20%
+	RetryableStatusCodes []int         // Status codes that trigger a retry
+	RetryableErrors      []string      // Error classes that trigger a retry
+}
+
+// DefaultRetryPolicy returns a conservative policy for transient failures.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       500 * time.Millisecond,
+		MaxBackoff:           10 * time.Second,
+		Multiplier:           2,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{408, 429, 500, 502, 503, 504},
+		RetryableErrors:      []string{ErrorClassTimeout, ErrorClassConnection, ErrorClassDNS},
+	}
+}
+
+// ShouldRetry reports whether a request that ended with statusCode or err, after the given
+// number of attempts, should be tried again.
+func (p RetryPolicy) ShouldRetry(attempts, statusCode int, err error) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	if err != nil {
+		class := classifyError(err)
+		return class != "" && slices.Contains(p.RetryableErrors, class)
+	}
+	return slices.Contains(p.RetryableStatusCodes, statusCode)
+}
+
+// Backoff returns the pause before the attempt following the given number of attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for range attempts - 1 {
+		d *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// classifyError maps a request error to one of the retryable error classes, or "" if it is permanent.
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTemporary || dnsErr.IsTimeout {
+			return ErrorClassDNS
+		}
+		return ""
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorClassConnection
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxRobotsSize is how much of a robots.txt file is read, the same limit Google applies.
+const maxRobotsSize = 500 << 10
+
+// robotsSitemaps returns the sitemap URLs listed in a robots.txt file, resolved against its URL.
+func robotsSitemaps(body []byte, base *url.URL) []string {
+	var sitemaps []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			sitemaps = append(sitemaps, resolveURL(base, value))
+		}
+	}
+	return sitemaps
+}
+
+// isNoindex reports whether a response asks not to be indexed, through an X-Robots-Tag
+// header or, for HTML pages, a robots meta tag.
+func isNoindex(header http.Header, body []byte) bool {
+	if headerNoindex(header) {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return false
+	}
+	return metaNoindex(body)
+}
+
+// headerNoindex reports whether an X-Robots-Tag header forbids indexing.
+func headerNoindex(header http.Header) bool {
+	for _, value := range header.Values("X-Robots-Tag") {
+		if hasNoindex(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNoindex reports whether a list of robots directives such as "noindex, nofollow" or
+// "googlebot: none" forbids indexing.
+func hasNoindex(directives string) bool {
+	for _, directive := range strings.Split(strings.ToLower(directives), ",") {
+		// Drop a user agent prefix; "unavailable_after: <date>" never matches anyway.
+		if _, rest, ok := strings.Cut(directive, ":"); ok {
+			directive = rest
+		}
+		if d := strings.TrimSpace(directive); d == "noindex" || d == "none" {
+			return true
+		}
+	}
+	return false
+}
+
+// metaNoindex looks for a robots meta tag forbidding indexing in the head of an HTML page.
+func metaNoindex(body []byte) bool {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.Data == "body" {
+				return false
+			}
+			if token.Data != "meta" {
+				continue
+			}
+			var name, content string
+			for _, attr := range token.Attr {
+				switch attr.Key {
+				case "name":
+					name = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "content":
+					content = attr.Val
+				}
+			}
+			if name == "robots" && hasNoindex(content) {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ScopeMode selects how links are classified as internal or external.
+type ScopeMode string
+
+const (
+	// ScopeHost treats a link as internal when its host equals the target host, ignoring case,
+	// ports, a trailing dot and a leading "www.".
+	ScopeHost ScopeMode = "host"
+	// ScopeDomain treats every host under the target's registrable domain (eTLD+1) as internal.
+	ScopeDomain ScopeMode = "domain"
+	// ScopeAllowlist treats the target host plus every host on the allowlist as internal.
+	// Entries starting with "*." match any subdomain of the rest of the entry.
+	ScopeAllowlist ScopeMode = "allowlist"
+)
+
+// Scope decides which links belong to the crawled site.
+type Scope struct {
+	Mode      ScopeMode
+	Allowlist []string
+}
+
+// IsInternal reports whether link is part of the same site as base according to the scope rules.
+func (s Scope) IsInternal(base *url.URL, link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if parsed.Host == "" {
+		return true // Relative links always stay on the same site.
+	}
+
+	baseHost, linkHost := canonicalHostname(base.Host), canonicalHostname(parsed.Host)
+	if sameHost(baseHost, linkHost) {
+		return true
+	}
+
+	switch s.Mode {
+	case ScopeDomain:
+		baseDomain, linkDomain := registrableDomain(baseHost), registrableDomain(linkHost)
+		return baseDomain != "" && baseDomain == linkDomain
+	case ScopeAllowlist:
+		for _, entry := range s.Allowlist {
+			if matchesAllowlistEntry(linkHost, entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canonicalHostname strips the port and trailing dot from a host and converts it to lowercase ASCII.
+func canonicalHostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// sameHost compares two canonical hostnames, treating "www." as insignificant.
+func sameHost(a, b string) bool {
+	return strings.TrimPrefix(a, "www.") == strings.TrimPrefix(b, "www.")
+}
+
+// registrableDomain returns the eTLD+1 of a hostname using the public suffix list,
+// or the hostname itself for IP addresses and single-label hosts such as localhost.
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// matchesAllowlistEntry checks a canonical hostname against a single allowlist entry.
+func matchesAllowlistEntry(host, entry string) bool {
+	if suffix, ok := strings.CutPrefix(entry, "*."); ok {
+		suffix = canonicalHostname(suffix)
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return sameHost(host, canonicalHostname(entry))
+}
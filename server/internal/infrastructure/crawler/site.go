@@ -0,0 +1,238 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Crawl modes.
+const (
+	ModePage = "page" // Analyse the target page and check its links
+	ModeSite = "site" // Also crawl the target's site by following its internal links
+)
+
+// SitePage is a page reached while crawling a site.
+type SitePage struct {
+	URL           string     `json:"url"`
+	NormalizedURL string     `json:"normalized_url"`
+	Depth         int        `json:"depth"` // Links followed from the target to reach the page
+	StatusCode    int        `json:"status_code"`
+	RedirectURL   string     `json:"redirect_url,omitempty"`
+	ContentType   string     `json:"content_type"`
+	Title         string     `json:"title"`
+	LastModified  *time.Time `json:"last_modified,omitempty"` // From the Last-Modified header
+	Noindex       bool       `json:"noindex"`
+	CanonicalURL  string     `json:"canonical_url,omitempty"`
+	Indexable     bool       `json:"indexable"` // A 200 HTML page that is neither noindex nor canonicalised elsewhere
+	Error         string     `json:"error,omitempty"`
+	Truncated     string     `json:"truncated,omitempty"` // The limits the page hit, so that it was analysed only in part
+	Links         []SiteLink `json:"links,omitempty"`     // Internal links of the page, in page order
+
+	// Link graph metrics, see analyzeGraph.
+	Inlinks  int     `json:"inlinks"`  // Crawled pages linking to the page
+	Outlinks int     `json:"outlinks"` // Internal links and redirect of the page, crawled or not
+	PageRank float64 `json:"pagerank"`
+	DeadEnd  bool    `json:"dead_end"` // A 200 HTML page without internal links
+}
+
+// SiteLink is an internal link found on a site page.
+type SiteLink struct {
+	URL           string `json:"url"`
+	NormalizedURL string `json:"normalized_url"`
+	AnchorText    string `json:"anchor_text"`
+}
+
+// crawlSite crawls the target's site breadth-first, starting with the target itself and following
+// internal links and redirects up to opts.MaxDepth links away, until opts.MaxPages are fetched.
+// URLs that look like spider traps aren't followed and are reported in the returned warning.
+// Page fetches are charged to the link budget, through info.
+func (wc *WebCrawler) crawlSite(target *url.URL, opts Options, sess *session, info *PageInfo) ([]SitePage, *Warning) {
+	traps := newTrapDetector(wc.cfg.Traps)
+	seen := make(map[string]struct{})
+	visit := func(link string) bool {
+		normalized, err := opts.Normalizer.Normalize(link)
+		if err != nil {
+			normalized = link
+		}
+		if _, ok := seen[normalized]; ok {
+			return false
+		}
+		seen[normalized] = struct{}{}
+		return true
+	}
+
+	var pages []SitePage
+	frontier := []string{target.String()}
+	visit(target.String())
+	for depth := 0; len(frontier) > 0 && depth <= opts.MaxDepth; depth++ {
+		frontier = frontier[:min(len(frontier), opts.MaxPages-len(pages))]
+		granted := info.grantRequests(opts, len(frontier))
+		budgetCut := granted < len(frontier)
+		if budgetCut {
+			info.addTruncation(fmt.Sprintf("the site crawl could fetch only %d pages within the link check quota", len(pages)+granted))
+			frontier = frontier[:granted]
+		}
+
+		// Fetch a whole level with the same bounded pool of workers as the link checks.
+		level := make([]SitePage, len(frontier))
+		fetched := make([]bool, len(frontier))
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		var panics panicCarrier
+		for range min(wc.cfg.LinkCheckWorkers, len(frontier)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					panics.run(func() { level[i], fetched[i] = wc.fetchSitePage(frontier[i], depth, target, opts, sess) })
+				}
+			}()
+		}
+		dispatched := 0
+	dispatch:
+		for i := range frontier {
+			select {
+			case jobs <- i:
+				dispatched++
+			case <-sess.ctx.Done():
+				break dispatch
+			}
+		}
+		close(jobs)
+		info.returnRequests(opts, len(frontier)-dispatched)
+		wg.Wait()
+		panics.raise()
+		// Pages the crawl didn't get to fetch before it had to stop are left out.
+		for i, page := range level {
+			if fetched[i] {
+				pages = append(pages, page)
+			}
+		}
+		if sess.ctx.Err() != nil {
+			break // Pages are only unfetched when the crawl stops, so the level was complete otherwise
+		}
+		if budgetCut {
+			break
+		}
+
+		var next []string
+		for _, page := range level {
+			var candidates []string
+			if page.RedirectURL != "" {
+				candidates = append(candidates, page.RedirectURL)
+			}
+			for _, link := range page.Links {
+				candidates = append(candidates, link.URL)
+			}
+			for _, link := range candidates {
+				if opts.Scope.IsInternal(target, link) && visit(link) && traps.allow(link) {
+					next = append(next, link)
+				}
+			}
+		}
+		frontier = next
+	}
+	if n := countTruncated(pages); n > 0 {
+		info.addTruncation(fmt.Sprintf("%d site pages hit limits and were analysed only in part", n))
+	}
+	return pages, traps.warning()
+}
+
+// fetchSitePage fetches a single page of a site without following redirects, so that each
+// redirect is recorded as its own page, and extracts what is needed to judge its indexability.
+// It reports false when the crawl stopped before the page was fetched.
+func (wc *WebCrawler) fetchSitePage(link string, depth int, target *url.URL, opts Options, sess *session) (SitePage, bool) {
+	page := SitePage{URL: link, NormalizedURL: link, Depth: depth}
+	if normalized, err := opts.Normalizer.Normalize(link); err == nil {
+		page.NormalizedURL = normalized
+	}
+
+	// Limits hit while fetching and analysing the page are recorded on the page, like those of
+	// the target on the crawl.
+	truncated := &truncation{}
+
+	// Site pages belong to the target, so they are authenticated like the target itself.
+	client := noRedirects(sess.clientFor(link, true))
+	resp, body, err := wc.fetch(withTruncation(sess.ctx, truncated), link, opts, client, sess.authenticates(link, true), wc.cfg.Limits.MaxBodySize)
+	if interrupted(sess.ctx, err) {
+		return page, false
+	}
+	sess.progress.pageVisited()
+	if err != nil {
+		page.Error = err.Error()
+		return page, true
+	}
+	page.StatusCode = resp.StatusCode
+	if location, err := resp.Location(); err == nil {
+		page.RedirectURL = location.String()
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		page.LastModified = &modified
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, kind := detectResource(contentType, body)
+	page.ContentType = mediaType
+	page.Noindex = headerNoindex(resp.Header)
+	if resp.StatusCode != http.StatusOK || kind != ResourceHTML {
+		return page, true
+	}
+
+	body, _ = decodeBody(body, contentType)
+	body = capDOMElements(body, wc.cfg.Limits.MaxDOMElements, truncated)
+	page.Noindex = page.Noindex || metaNoindex(body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		page.Error = err.Error()
+		return page, true
+	}
+	base := resp.Request.URL
+	page.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	if href := strings.TrimSpace(doc.Find(`link[rel~="canonical"]`).First().AttrOr("href", "")); href != "" {
+		page.CanonicalURL = resolveURL(base, href)
+	}
+
+	var found []foundLink
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		if href := s.AttrOr("href", ""); isFollowableHref(href) {
+			found = append(found, foundLink{URL: resolveURL(base, href), AnchorText: strings.Join(strings.Fields(s.Text()), " ")})
+		}
+	})
+	for _, l := range getUniqueLinks(found, opts.Normalizer) {
+		if !opts.Scope.IsInternal(target, l.URL) {
+			continue
+		}
+		if len(page.Links) == wc.cfg.Limits.MaxLinksPerPage {
+			truncated.record("page has more than %d internal links", wc.cfg.Limits.MaxLinksPerPage)
+			break
+		}
+		page.Links = append(page.Links, SiteLink{URL: l.URL, NormalizedURL: l.NormalizedURL, AnchorText: l.AnchorText})
+	}
+	if warning := truncated.warning(); warning != nil {
+		page.Truncated = warning.Message
+	}
+
+	page.Indexable = !page.Noindex
+	if page.CanonicalURL != "" {
+		canonical, err := opts.Normalizer.Normalize(page.CanonicalURL)
+		page.Indexable = page.Indexable && err == nil && canonical == page.NormalizedURL
+	}
+	return page, true
+}
+
+// countTruncated counts the pages that were analysed only in part.
+func countTruncated(pages []SitePage) int {
+	n := 0
+	for _, page := range pages {
+		if page.Truncated != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,461 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of the sitemap protocol (https://www.sitemaps.org/protocol.html).
+const (
+	maxSitemapURLs  = 50000
+	maxSitemapBytes = 50 << 20 // Uncompressed
+)
+
+const (
+	maxSitemapFiles    = 100       // Sitemaps fetched per crawl, indexes included
+	maxSitemapIssues   = 100       // Validation issues listed per sitemap
+	maxSitemapFindings = 200       // URLs listed per finding; the counts cover all of them
+	noindexScanSize    = 256 << 10 // Bytes of a page searched for a robots meta tag
+)
+
+// Where a sitemap was found.
+const (
+	SitemapSourceRobots    = "robots"    // A Sitemap line of robots.txt
+	SitemapSourceDefault   = "default"   // The conventional /sitemap.xml
+	SitemapSourceIndex     = "index"     // Listed by a sitemap index
+	SitemapSourceSubmitted = "submitted" // Submitted by the user
+)
+
+// ErrInvalidSitemap is returned when a submitted sitemap can't be fetched or parsed.
+var ErrInvalidSitemap = errors.New("invalid sitemap")
+
+// gzipMagic starts every gzip stream; sitemaps are often served gzipped as application/octet-stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// w3cDatetimeLayouts are the W3C Datetime forms a lastmod may take.
+var w3cDatetimeLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+}
+
+// SitemapReport is the result of validating a target's sitemaps and checking the URLs they list.
+type SitemapReport struct {
+	Sitemaps   []SitemapFile  `json:"sitemaps"`
+	URLCount   int            `json:"url_count"` // Unique URLs across all sitemaps
+	Checked    int            `json:"checked"`   // URLs requested, at most the links-per-page limit
+	Broken     SitemapFinding `json:"broken"`
+	Redirected SitemapFinding `json:"redirected"`
+	Noindexed  SitemapFinding `json:"noindexed"`
+	NotLinked  SitemapFinding `json:"not_linked"` // Not linked from the crawled page; covers every URL
+}
+
+// SitemapFile describes a single sitemap or sitemap index.
+type SitemapFile struct {
+	URL        string   `json:"url"`
+	Source     string   `json:"source"`         // robots, default, index, submitted
+	Type       string   `json:"type,omitempty"` // urlset, sitemapindex, or html when a page was served instead
+	StatusCode int      `json:"status_code"`
+	Compressed bool     `json:"compressed"`
+	Entries    int      `json:"entries"`         // URLs, or sitemaps for an index
+	Error      string   `json:"error,omitempty"` // Set when the sitemap couldn't be fetched or parsed
+	Issues     []string `json:"issues,omitempty"`
+	IssueCount int      `json:"issue_count"`
+}
+
+// SitemapFinding lists the sitemap URLs that have a given problem.
+type SitemapFinding struct {
+	Count int               `json:"count"`
+	URLs  []SitemapURLCheck `json:"urls,omitempty"` // The first of them
+}
+
+// SitemapURLCheck is the outcome of requesting a URL listed in a sitemap.
+type SitemapURLCheck struct {
+	URL         string `json:"url"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	RedirectURL string `json:"redirect_url,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// sitemapDocument maps both a urlset and a sitemapindex.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// sitemapURL is a URL listed in a sitemap, with its normalised form for comparisons.
+type sitemapURL struct {
+	url        string
+	normalized string
+}
+
+// sitemapSet fetches sitemaps, following indexes, and gathers the URLs they list.
+type sitemapSet struct {
+	wc       *WebCrawler
+	opts     Options
+	sess     *session
+	maxURLs  int // No further sitemaps are fetched once this many URLs are known; 0 for no limit
+	queue    []SitemapFile
+	seen     map[string]struct{}
+	files    []SitemapFile
+	urls     []sitemapURL
+	seenURLs map[string]struct{}
+}
+
+func newSitemapSet(wc *WebCrawler, opts Options, sess *session, maxURLs int) *sitemapSet {
+	return &sitemapSet{
+		wc:       wc,
+		opts:     opts,
+		sess:     sess,
+		maxURLs:  maxURLs,
+		seen:     make(map[string]struct{}),
+		seenURLs: make(map[string]struct{}),
+	}
+}
+
+// add queues a sitemap unless it is already known.
+func (s *sitemapSet) add(rawURL, source string) {
+	if _, ok := s.seen[rawURL]; ok {
+		return
+	}
+	s.seen[rawURL] = struct{}{}
+	s.queue = append(s.queue, SitemapFile{URL: rawURL, Source: source})
+}
+
+// walk fetches the queued sitemaps and those their indexes list, until the session is cancelled.
+func (s *sitemapSet) walk() {
+	for len(s.queue) > 0 && len(s.files) < maxSitemapFiles {
+		if s.maxURLs > 0 && len(s.urls) >= s.maxURLs || s.sess.ctx.Err() != nil {
+			return
+		}
+		file := s.queue[0]
+		s.queue = s.queue[1:]
+		s.load(&file)
+		s.files = append(s.files, file)
+	}
+}
+
+// load fetches, parses and validates a single sitemap.
+func (s *sitemapSet) load(file *SitemapFile) {
+	base, err := url.Parse(file.URL)
+	if err != nil {
+		file.Error = err.Error()
+		return
+	}
+
+	// Sitemaps may be larger than a page; note when the body limits cut one short.
+	tracker := &truncation{}
+	ctx := withTruncation(s.sess.ctx, tracker)
+	client := s.sess.clientFor(file.URL, true)
+	resp, body, err := s.wc.fetch(ctx, file.URL, s.opts, client, s.sess.authenticates(file.URL, true), maxSitemapBytes)
+	if err != nil {
+		file.Error = err.Error()
+		return
+	}
+	file.StatusCode = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		file.Error = fmt.Sprintf("unexpected status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		return
+	}
+	if bytes.HasPrefix(body, gzipMagic) {
+		file.Compressed = true
+		if body, err = gunzip(body, maxSitemapBytes, tracker); err != nil {
+			file.Error = fmt.Sprintf("invalid gzip data: %v", err)
+			return
+		}
+	}
+	if sniffMediaType(body) == "text/html" {
+		file.Type = "html"
+		file.Error = "served an HTML page, not a sitemap"
+		return
+	}
+	if warning := tracker.warning(); warning != nil {
+		file.addIssue("%s", warning.Message)
+	}
+
+	var doc sitemapDocument
+	if err := newXMLDecoder(body).Decode(&doc); err != nil {
+		file.Error = fmt.Sprintf("invalid XML: %v", err)
+		return
+	}
+	file.Type = doc.XMLName.Local
+	switch file.Type {
+	case "urlset":
+		file.Entries = len(doc.URLs)
+		if file.Entries > maxSitemapURLs {
+			file.addIssue("lists %d URLs, more than the %d allowed", file.Entries, maxSitemapURLs)
+		}
+		for _, entry := range doc.URLs {
+			if loc, ok := file.validate(base, entry); ok {
+				s.addURL(loc)
+			}
+		}
+	case "sitemapindex":
+		file.Entries = len(doc.Sitemaps)
+		if file.Entries > maxSitemapURLs {
+			file.addIssue("lists %d sitemaps, more than the %d allowed", file.Entries, maxSitemapURLs)
+		}
+		if file.Source == SitemapSourceIndex {
+			file.addIssue("sitemap indexes must not be nested")
+		}
+		for _, entry := range doc.Sitemaps {
+			if loc, ok := file.validate(base, entry); ok {
+				s.add(loc, SitemapSourceIndex)
+			}
+		}
+	default:
+		file.Error = fmt.Sprintf("root element is <%s>, not <urlset> or <sitemapindex>", file.Type)
+	}
+}
+
+// addURL records a URL unless an equal one is already known.
+func (s *sitemapSet) addURL(loc string) {
+	normalized, err := s.opts.Normalizer.Normalize(loc)
+	if err != nil {
+		normalized = loc
+	}
+	if _, ok := s.seenURLs[normalized]; ok {
+		return
+	}
+	s.seenURLs[normalized] = struct{}{}
+	s.urls = append(s.urls, sitemapURL{url: loc, normalized: normalized})
+}
+
+// validate checks the loc and lastmod of an entry and returns its URL if it can be used.
+// URLs on another host are reported but still returned.
+func (f *SitemapFile) validate(base *url.URL, entry sitemapEntry) (string, bool) {
+	loc := strings.TrimSpace(entry.Loc)
+	u, err := url.Parse(loc)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		f.addIssue("%q is not an absolute http(s) URL", loc)
+		return "", false
+	}
+	if canonicalHostname(u.Host) != canonicalHostname(base.Host) {
+		f.addIssue("%s is not on the sitemap's host %s", loc, base.Host)
+	}
+	if lastMod := strings.TrimSpace(entry.LastMod); lastMod != "" && !isW3CDatetime(lastMod) {
+		f.addIssue("%s has a lastmod %q that is not a W3C datetime", loc, entry.LastMod)
+	}
+	return loc, true
+}
+
+func (f *SitemapFile) addIssue(format string, args ...any) {
+	f.IssueCount++
+	if len(f.Issues) < maxSitemapIssues {
+		f.Issues = append(f.Issues, fmt.Sprintf(format, args...))
+	}
+}
+
+func (f *SitemapFinding) add(check SitemapURLCheck) {
+	f.Count++
+	if len(f.URLs) < maxSitemapFindings {
+		f.URLs = append(f.URLs, check)
+	}
+}
+
+func isW3CDatetime(value string) bool {
+	for _, layout := range w3cDatetimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// gunzip decompresses a gzipped sitemap, reading at most max bytes of it and noting on tracker
+// when it is larger.
+func gunzip(body []byte, max int64, tracker *truncation) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > max {
+		tracker.record("sitemap exceeded %d bytes uncompressed", max)
+		out = out[:max]
+	}
+	return out, nil
+}
+
+// discoverSitemaps fetches the target's sitemaps, found through robots.txt and /sitemap.xml.
+func (wc *WebCrawler) discoverSitemaps(target *url.URL, opts Options, sess *session) *sitemapSet {
+	set := newSitemapSet(wc, opts, sess, 0)
+	robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+	robots := robotsURL.String()
+	resp, body, err := wc.fetch(sess.ctx, robots, opts, sess.clientFor(robots, true), sess.authenticates(robots, true), maxRobotsSize)
+	if err == nil && resp.StatusCode == http.StatusOK {
+		for _, sitemap := range robotsSitemaps(body, robotsURL) {
+			set.add(sitemap, SitemapSourceRobots)
+		}
+	}
+	set.add((&url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/sitemap.xml"}).String(), SitemapSourceDefault)
+	set.walk()
+	return set
+}
+
+// checkSitemaps reports on the validity of a set of discovered sitemaps and checks the URLs
+// they list. The page's links in info are used to find unlinked URLs.
+func (wc *WebCrawler) checkSitemaps(set *sitemapSet, target *url.URL, opts Options, sess *session, info *PageInfo) *SitemapReport {
+	report := &SitemapReport{Sitemaps: make([]SitemapFile, 0, len(set.files)), URLCount: len(set.urls)}
+	for _, file := range set.files {
+		// Not having a sitemap at the conventional location is not a problem, and sites that
+		// answer every path with a page don't have one either.
+		if file.Source == SitemapSourceDefault && (file.StatusCode == http.StatusNotFound || file.Type == "html") {
+			continue
+		}
+		report.Sitemaps = append(report.Sitemaps, file)
+	}
+
+	linked := make(map[string]struct{}, len(info.Links)+1)
+	if normalized, err := opts.Normalizer.Normalize(target.String()); err == nil {
+		linked[normalized] = struct{}{}
+	}
+	for _, link := range info.Links {
+		linked[link.NormalizedURL] = struct{}{}
+	}
+	for _, u := range set.urls {
+		if _, ok := linked[u.normalized]; !ok {
+			report.NotLinked.add(SitemapURLCheck{URL: u.url})
+		}
+	}
+
+	// Request the URLs with the same bounded pool of workers as the page's links.
+	toCheck := set.urls[:min(len(set.urls), wc.cfg.Limits.MaxLinksPerPage)]
+	if granted := info.grantRequests(opts, len(toCheck)); granted < len(toCheck) {
+		info.addTruncation(fmt.Sprintf("only %d of its %d sitemap URLs could be checked within the link check quota", granted, len(toCheck)))
+		toCheck = toCheck[:granted]
+	}
+	report.Checked = len(toCheck)
+	results := make([]sitemapURLResult, len(toCheck))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var panics panicCarrier
+	for range min(wc.cfg.LinkCheckWorkers, len(toCheck)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				panics.run(func() { results[i] = wc.checkSitemapURL(toCheck[i].url, opts, sess) })
+			}
+		}()
+	}
+	dispatched := 0
+dispatch:
+	for i := range toCheck {
+		select {
+		case jobs <- i:
+			dispatched++
+		case <-sess.ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	info.returnRequests(opts, len(toCheck)-dispatched)
+	wg.Wait()
+	panics.raise()
+
+	for _, result := range results {
+		switch {
+		case result.blocked:
+			// Never requested, like blocked links.
+		case result.StatusCode == 0 || result.StatusCode >= 400:
+			report.Broken.add(result.SitemapURLCheck)
+		case result.StatusCode >= 300:
+			report.Redirected.add(result.SitemapURLCheck)
+		case result.noindex:
+			report.Noindexed.add(result.SitemapURLCheck)
+		}
+	}
+	return report
+}
+
+type sitemapURLResult struct {
+	SitemapURLCheck
+	noindex bool
+	blocked bool
+}
+
+// checkSitemapURL requests a URL listed in a sitemap without following redirects: a sitemap
+// should only list the final, indexable URL of each page.
+func (wc *WebCrawler) checkSitemapURL(link string, opts Options, sess *session) sitemapURLResult {
+	result := sitemapURLResult{SitemapURLCheck: SitemapURLCheck{URL: link}}
+	client := noRedirects(sess.clientFor(link, false))
+	resp, body, err := wc.fetch(sess.ctx, link, opts, client, sess.authenticates(link, false), noindexScanSize)
+	if isBlocked(err) {
+		result.blocked = true
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.StatusCode = resp.StatusCode
+	if location, err := resp.Location(); err == nil {
+		result.RedirectURL = location.String()
+	}
+	result.noindex = resp.StatusCode == http.StatusOK && isNoindex(resp.Header, body)
+	return result
+}
+
+// noRedirects returns a copy of client that returns redirect responses instead of following them.
+func noRedirects(client *http.Client) *http.Client {
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &c
+}
+
+// SitemapURLs fetches a sitemap, following it if it is an index, and returns up to limit of the
+// URLs it lists, along with the number of URLs found in the sitemaps that were fetched. Cancelling
+// ctx stops the fetches.
+func (wc *WebCrawler) SitemapURLs(ctx context.Context, sitemapURL string, opts Options, limit int) ([]string, int, error) {
+	target, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidSitemap, err)
+	}
+	sess, err := wc.newSession(ctx, target, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer sess.close()
+
+	set := newSitemapSet(wc, opts, sess, limit)
+	set.add(sitemapURL, SitemapSourceSubmitted)
+	set.walk()
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if root := set.files[0]; root.Error != "" {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidSitemap, root.Error)
+	}
+
+	urls := make([]string, 0, min(limit, len(set.urls)))
+	for _, u := range set.urls[:min(limit, len(set.urls))] {
+		urls = append(urls, u.url)
+	}
+	return urls, len(set.urls), nil
+}
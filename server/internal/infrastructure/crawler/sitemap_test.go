@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGunzipReportsTruncation(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(strings.Repeat("a", 100)))
+	zw.Close()
+
+	tests := []struct {
+		name      string
+		max       int64
+		wantLen   int
+		truncated bool
+	}{
+		{"within the limit", 100, 100, false},
+		{"over the limit", 60, 60, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &truncation{}
+			body, err := gunzip(compressed.Bytes(), tt.max, tracker)
+			if err != nil {
+				t.Fatalf("gunzip: %v", err)
+			}
+			if len(body) != tt.wantLen {
+				t.Errorf("len(body) = %d, want %d", len(body), tt.wantLen)
+			}
+			if truncated := tracker.warning() != nil; truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestSitemapURLsStopsWhenCancelled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>` + "http://" + r.Host + `/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	policy, err := ParseNetworkPolicy([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := NewWebCrawler(Config{Network: policy}, NewMemoryLinkCache(100, CacheTTLs{}), NewHostLimiter(LimiterConfig{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := wc.SitemapURLs(ctx, server.URL+"/sitemap.xml", wc.DefaultOptions(), 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("SitemapURLs() error = %v, want %v", err, context.Canceled)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("a cancelled SitemapURLs sent %d requests", n)
+	}
+
+	urls, total, err := wc.SitemapURLs(context.Background(), server.URL+"/sitemap.xml", wc.DefaultOptions(), 10)
+	if err != nil || total != 1 || len(urls) != 1 {
+		t.Errorf("SitemapURLs() = %v, %d, %v, want one URL", urls, total, err)
+	}
+}
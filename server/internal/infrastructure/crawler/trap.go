@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// WarningSpiderTrap flags a site crawl that stopped following URLs which looked like a spider
+// trap, such as an endless calendar, faceted navigation or session IDs in URLs.
+const WarningSpiderTrap = "SPIDER_TRAP"
+
+// Reasons a URL is considered part of a spider trap.
+const (
+	TrapURLTooLong        = "URL too long"
+	TrapRepeatingSegments = "repeating path segments"
+	TrapSessionID         = "session ID in URL"
+	TrapQueryVariants     = "too many query parameter combinations"
+	TrapPatternCap        = "too many pages with the same URL pattern"
+)
+
+// trapExamples is the number of trapped URLs a warning lists per reason.
+const trapExamples = 3
+
+// TrapLimits are the heuristics site crawls use to detect infinite URL spaces.
+type TrapLimits struct {
+	MaxURLLength       int `json:"max_url_length"`
+	MaxSegmentRepeats  int `json:"max_segment_repeats"`   // Occurrences of the same segment in a path
+	MaxQueryVariants   int `json:"max_query_variants"`    // Distinct query strings of a single path
+	MaxPagesPerPattern int `json:"max_pages_per_pattern"` // Pages sharing a URL pattern, numbers and query values aside
+}
+
+// DefaultTrapLimits returns the limits used when none are configured.
+func DefaultTrapLimits() TrapLimits {
+	return TrapLimits{
+		MaxURLLength:       1024,
+		MaxSegmentRepeats:  3,
+		MaxQueryVariants:   50,
+		MaxPagesPerPattern: 100,
+	}
+}
+
+// sessionParams are query parameters, in lower case, that carry a session ID.
+var sessionParams = map[string]struct{}{
+	"sid":          {},
+	"sessid":       {},
+	"sessionid":    {},
+	"session_id":   {},
+	"phpsessid":    {},
+	"jsessionid":   {},
+	"aspsessionid": {},
+	"cfid":         {},
+	"cftoken":      {},
+}
+
+// digitRun matches the numbers in a path, so that /2024/05 and /2024/06 share a pattern.
+var digitRun = regexp.MustCompile(`[0-9]+`)
+
+// trapDetector decides which URLs a site crawl stops following. It counts the URLs it accepts,
+// so it must see each distinct URL only once.
+type trapDetector struct {
+	limits   TrapLimits
+	queries  map[string]map[string]struct{} // Distinct query strings per path
+	patterns map[string]int                 // Accepted URLs per pattern
+	trapped  map[string][]string            // Trapped URLs per reason
+	reasons  []string                       // Reasons in the order they were first hit
+}
+
+func newTrapDetector(limits TrapLimits) *trapDetector {
+	return &trapDetector{
+		limits:   limits,
+		queries:  make(map[string]map[string]struct{}),
+		patterns: make(map[string]int),
+		trapped:  make(map[string][]string),
+	}
+}
+
+// allow reports whether link may be followed, recording it as trapped when it may not.
+func (d *trapDetector) allow(link string) bool {
+	reason := d.check(link)
+	if reason == "" {
+		return true
+	}
+	if _, ok := d.trapped[reason]; !ok {
+		d.reasons = append(d.reasons, reason)
+	}
+	d.trapped[reason] = append(d.trapped[reason], link)
+	return false
+}
+
+// check returns why link looks like a spider trap, or an empty string if it doesn't.
+func (d *trapDetector) check(link string) string {
+	if len(link) > d.limits.MaxURLLength {
+		return TrapURLTooLong
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	// Servlet containers append the session to the path, as in /page;jsessionid=...
+	if strings.Contains(strings.ToLower(u.Path), ";jsessionid=") {
+		return TrapSessionID
+	}
+	query := u.Query()
+	for name := range query {
+		if _, ok := sessionParams[strings.ToLower(name)]; ok {
+			return TrapSessionID
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "" {
+			continue
+		}
+		if counts[segment]++; counts[segment] > d.limits.MaxSegmentRepeats {
+			return TrapRepeatingSegments
+		}
+	}
+
+	path := u.Host + u.Path
+	encoded := query.Encode() // Sorted by name, so parameter order doesn't matter
+	variants := d.queries[path]
+	_, known := variants[encoded]
+	if len(query) > 0 && !known && len(variants) >= d.limits.MaxQueryVariants {
+		return TrapQueryVariants
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pattern := u.Host + digitRun.ReplaceAllString(u.Path, "{n}") + "?" + strings.Join(names, "&")
+	if d.patterns[pattern] >= d.limits.MaxPagesPerPattern {
+		return TrapPatternCap
+	}
+
+	d.patterns[pattern]++
+	if len(query) > 0 {
+		if variants == nil {
+			variants = make(map[string]struct{})
+			d.queries[path] = variants
+		}
+		variants[encoded] = struct{}{}
+	}
+	return ""
+}
+
+// warning returns the SPIDER_TRAP warning describing the trapped URLs, or nil if there are none.
+func (d *trapDetector) warning() *Warning {
+	if len(d.reasons) == 0 {
+		return nil
+	}
+	total := 0
+	parts := make([]string, 0, len(d.reasons))
+	for _, reason := range d.reasons {
+		urls := d.trapped[reason]
+		total += len(urls)
+		parts = append(parts, fmt.Sprintf("%s (%d, e.g. %s)", reason, len(urls), strings.Join(urls[:min(len(urls), trapExamples)], ", ")))
+	}
+	return &Warning{
+		Code:    WarningSpiderTrap,
+		Message: fmt.Sprintf("stopped following %d URLs that look like spider traps: %s", total, strings.Join(parts, "; ")),
+	}
+}
@@ -18,7 +18,7 @@ func ConnectDB(dsn string) *gorm.DB {
 	log.Println("Database connection successfully established")
 
 	// Auto-migrate the schema to create/update tables.
-	err = db.AutoMigrate(&entity.User{}, &entity.Crawl{})
+	err = db.AutoMigrate(&entity.User{}, &entity.Crawl{}, &entity.CrawlLink{}, &entity.CrawlPage{}, &entity.CrawlEdge{}, &entity.CrawlBatch{}, &entity.DailyUsage{})
 	if err != nil {
 		log.Fatalf("failed to auto-migrate database: %v", err)
 	}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the in-memory store drops buckets that have refilled completely.
+const pruneInterval = time.Minute
+
+// Limit is a token bucket that holds Requests tokens and refills all of them over Window.
+// A zero Requests or Window disables the limit.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// rate returns the tokens the bucket refills per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // Whole tokens left after this request
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token is available, zero when allowed
+}
+
+// Store keeps the token buckets of a rate limiter. A shared implementation, e.g. backed by Redis,
+// lets several API instances enforce one budget. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes a token from the bucket of key, creating a full bucket if there is none.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the state of a single token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// MemoryStore keeps token buckets in process memory, so each API instance counts on its own.
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+	now        func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:    make(map[string]*bucket),
+		lastPruned: time.Now(),
+		now:        time.Now,
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPruned) >= pruneInterval {
+		s.prune(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.rate())
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = seconds((float64(limit.Requests) - b.tokens) / limit.rate())
+	return result, nil
+}
+
+// prune drops the buckets that are full again, since a new bucket starts out the same way.
+func (s *MemoryStore) prune(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastPruned = now
+}
+
+// refill adds the tokens earned since the bucket was last updated.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed*b.limit.rate())
+	}
+	b.updated = now
+}
+
+// seconds converts a number of seconds into a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
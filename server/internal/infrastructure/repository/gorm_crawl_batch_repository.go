@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+// gormCrawlBatchRepository is the GORM implementation of the CrawlBatchRepository.
+type gormCrawlBatchRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCrawlBatchRepository creates a new instance of gormCrawlBatchRepository.
+func NewGormCrawlBatchRepository(db *gorm.DB) repository.CrawlBatchRepository {
+	return &gormCrawlBatchRepository{db: db}
+}
+
+// Create saves a new batch record to the database.
+func (r *gormCrawlBatchRepository) Create(ctx context.Context, batch *entity.CrawlBatch) error {
+	return r.db.WithContext(ctx).Create(batch).Error
+}
+
+// Update modifies an existing batch record in the database.
+func (r *gormCrawlBatchRepository) Update(ctx context.Context, batch *entity.CrawlBatch) error {
+	return r.db.WithContext(ctx).Save(batch).Error
+}
+
+// FindByID retrieves a single batch record by its ID, ensuring it belongs to the specified user.
+func (r *gormCrawlBatchRepository) FindByID(ctx context.Context, id, userID uint) (*entity.CrawlBatch, error) {
+	var batch entity.CrawlBatch
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// MarkCompleted records when a batch completed. Only the first call for a batch updates it, so
+// that crawls finishing at the same time report the completion once.
+func (r *gormCrawlBatchRepository) MarkCompleted(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.CrawlBatch{}).
+		Where("id = ? AND completed_at IS NULL", id).
+		Update("completed_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
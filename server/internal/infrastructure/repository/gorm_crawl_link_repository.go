@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+// linkInsertBatchSize keeps single INSERT statements well below MySQL's placeholder limit.
+const linkInsertBatchSize = 500
+
+// gormCrawlLinkRepository is the GORM implementation of the CrawlLinkRepository.
+type gormCrawlLinkRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCrawlLinkRepository creates a new instance of gormCrawlLinkRepository.
+func NewGormCrawlLinkRepository(db *gorm.DB) repository.CrawlLinkRepository {
+	return &gormCrawlLinkRepository{db: db}
+}
+
+// ReplaceForCrawl deletes the existing links of a crawl and inserts the new ones in a single transaction.
+func (r *gormCrawlLinkRepository) ReplaceForCrawl(ctx context.Context, crawlID uint, links []entity.CrawlLink) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("crawl_id = ?", crawlID).Delete(&entity.CrawlLink{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		for i := range links {
+			links[i].CrawlID = crawlID
+		}
+		return tx.CreateInBatches(links, linkInsertBatchSize).Error
+	})
+}
+
+// FindByCrawlID retrieves a filtered and paginated list of links for a crawl.
+func (r *gormCrawlLinkRepository) FindByCrawlID(ctx context.Context, crawlID uint, filter repository.CrawlLinkFilter) ([]entity.CrawlLink, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.CrawlLink{}).Where("crawl_id = ?", crawlID)
+
+	if filter.LinkType != "" {
+		query = query.Where("link_type = ?", filter.LinkType)
+	}
+
+	switch filter.Status {
+	case "":
+	case "ok":
+		query = query.Where("status_code BETWEEN 200 AND 299")
+	case "redirect":
+		query = query.Where("redirect_target <> '' OR status_code BETWEEN 300 AND 399")
+	case "broken":
+		query = query.Where("(status_code = 0 OR status_code >= 400) AND blocked = ?", false)
+	case "error":
+		query = query.Where("status_code = 0 AND blocked = ?", false)
+	case "blocked":
+		query = query.Where("blocked = ?", true)
+	default:
+		// Anything else is treated as an exact status code; the handler validates the format.
+		code, err := strconv.Atoi(filter.Status)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("status_code = ?", code)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var links []entity.CrawlLink
+	offset := (filter.Page - 1) * filter.PageSize
+	err := query.Order("id asc").Offset(offset).Limit(filter.PageSize).Find(&links).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return links, total, nil
+}
+
+// FindAllByCrawlID retrieves every link of a crawl ordered by insertion.
+func (r *gormCrawlLinkRepository) FindAllByCrawlID(ctx context.Context, crawlID uint) ([]entity.CrawlLink, error) {
+	var links []entity.CrawlLink
+	err := r.db.WithContext(ctx).Where("crawl_id = ?", crawlID).Order("id asc").Find(&links).Error
+	return links, err
+}
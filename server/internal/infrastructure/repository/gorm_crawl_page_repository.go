@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+// gormCrawlPageRepository is the GORM implementation of the CrawlPageRepository.
+type gormCrawlPageRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCrawlPageRepository creates a new instance of gormCrawlPageRepository.
+func NewGormCrawlPageRepository(db *gorm.DB) repository.CrawlPageRepository {
+	return &gormCrawlPageRepository{db: db}
+}
+
+// ReplaceForCrawl deletes the existing pages and edges of a crawl and inserts the new ones in a single transaction.
+func (r *gormCrawlPageRepository) ReplaceForCrawl(ctx context.Context, crawlID uint, pages []entity.CrawlPage, edges []entity.CrawlEdge) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("crawl_id = ?", crawlID).Delete(&entity.CrawlPage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("crawl_id = ?", crawlID).Delete(&entity.CrawlEdge{}).Error; err != nil {
+			return err
+		}
+		if len(pages) > 0 {
+			for i := range pages {
+				pages[i].CrawlID = crawlID
+			}
+			if err := tx.CreateInBatches(pages, linkInsertBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(edges) == 0 {
+			return nil
+		}
+		for i := range edges {
+			edges[i].CrawlID = crawlID
+		}
+		return tx.CreateInBatches(edges, linkInsertBatchSize).Error
+	})
+}
+
+// FindIndexable retrieves the indexable pages of a crawl ordered by crawl order.
+func (r *gormCrawlPageRepository) FindIndexable(ctx context.Context, crawlID uint) ([]entity.CrawlPage, error) {
+	var pages []entity.CrawlPage
+	err := r.db.WithContext(ctx).Where("crawl_id = ? AND indexable = ?", crawlID, true).Order("id asc").Find(&pages).Error
+	return pages, err
+}
+
+// FindGraph retrieves all pages and edges of a crawl, both in the order they were stored.
+func (r *gormCrawlPageRepository) FindGraph(ctx context.Context, crawlID uint) ([]entity.CrawlPage, []entity.CrawlEdge, error) {
+	var pages []entity.CrawlPage
+	if err := r.db.WithContext(ctx).Where("crawl_id = ?", crawlID).Order("id asc").Find(&pages).Error; err != nil {
+		return nil, nil, err
+	}
+	var edges []entity.CrawlEdge
+	if err := r.db.WithContext(ctx).Where("crawl_id = ?", crawlID).Order("id asc").Find(&edges).Error; err != nil {
+		return nil, nil, err
+	}
+	return pages, edges, nil
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
 	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // gormCrawlRepository is the GORM implementation of the CrawlRepository.
@@ -23,6 +25,51 @@ func (r *gormCrawlRepository) Create(ctx context.Context, crawl *entity.Crawl) e
 	return r.db.WithContext(ctx).Create(crawl).Error
 }
 
+// CreateWithinActiveLimit saves a new pending crawl unless its user already has limit crawls
+// pending or processing.
+func (r *gormCrawlRepository) CreateWithinActiveLimit(ctx context.Context, crawl *entity.Crawl, limit int64) (bool, error) {
+	return r.withinActiveLimit(ctx, crawl, limit, func(tx *gorm.DB) error {
+		return tx.Create(crawl).Error
+	})
+}
+
+// UpdateWithinActiveLimit saves a crawl made pending again unless its user already has limit other
+// crawls pending or processing.
+func (r *gormCrawlRepository) UpdateWithinActiveLimit(ctx context.Context, crawl *entity.Crawl, limit int64) (bool, error) {
+	return r.withinActiveLimit(ctx, crawl, limit, func(tx *gorm.DB) error {
+		return tx.Save(crawl).Error
+	})
+}
+
+// withinActiveLimit runs save if the user of crawl has fewer than limit other crawls pending or
+// processing. The user's row is locked while the crawls are counted and saved, so that concurrent
+// submissions of the same user can't both take the last free slot.
+func (r *gormCrawlRepository) withinActiveLimit(ctx context.Context, crawl *entity.Crawl, limit int64, save func(tx *gorm.DB) error) (bool, error) {
+	if limit <= 0 {
+		return true, save(r.db.WithContext(ctx))
+	}
+	saved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user entity.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, crawl.UserID).Error; err != nil {
+			return err
+		}
+		var active int64
+		err := tx.Model(&entity.Crawl{}).
+			Where("user_id = ? AND status IN ? AND id <> ?", crawl.UserID, []string{"PENDING", "PROCESSING"}, crawl.ID).
+			Count(&active).Error
+		if err != nil || active >= limit {
+			return err
+		}
+		saved = true
+		return save(tx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return saved, nil
+}
+
 // FindByUserID retrieves all crawl records for a specific user.
 func (r *gormCrawlRepository) FindByUserID(ctx context.Context, userID uint) ([]entity.Crawl, error) {
 	var crawls []entity.Crawl
@@ -43,12 +90,62 @@ func (r *gormCrawlRepository) FindByID(ctx context.Context, id, userID uint) (*e
 	return &crawl, nil
 }
 
+// FindLatestCompletedByResultKey retrieves the most recent completed crawl with the given result key
+// updated after since, regardless of the user it belongs to.
+func (r *gormCrawlRepository) FindLatestCompletedByResultKey(ctx context.Context, resultKey string, since time.Time) (*entity.Crawl, error) {
+	var crawl entity.Crawl
+	err := r.db.WithContext(ctx).
+		Where("result_key = ? AND status = ? AND updated_at > ?", resultKey, "COMPLETED", since).
+		Order("updated_at desc").
+		First(&crawl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &crawl, nil
+}
+
+// CountActiveByUserID counts the crawls of a user that are pending or processing.
+func (r *gormCrawlRepository) CountActiveByUserID(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.Crawl{}).
+		Where("user_id = ? AND status IN ?", userID, []string{"PENDING", "PROCESSING"}).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByStatusForBatch counts the crawls of a batch per status. Deleted crawls aren't counted.
+func (r *gormCrawlRepository) CountByStatusForBatch(ctx context.Context, batchID uint) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.Crawl{}).
+		Select("status, COUNT(*) AS count").
+		Where("batch_id = ?", batchID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
 // Update modifies an existing crawl record in the database.
 // GORM's Save method will update all fields of the record if it has a primary key.
 func (r *gormCrawlRepository) Update(ctx context.Context, crawl *entity.Crawl) error {
 	return r.db.WithContext(ctx).Save(crawl).Error
 }
 
+// UpdateStatus sets only the status and error message of a crawl, leaving its other columns as they are.
+func (r *gormCrawlRepository) UpdateStatus(ctx context.Context, id uint, status, errorMessage string) error {
+	return r.db.WithContext(ctx).Model(&entity.Crawl{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "error_message": errorMessage}).Error
+}
+
 // Delete removes a single crawl record, ensuring it belongs to the user.
 func (r *gormCrawlRepository) Delete(ctx context.Context, id, userID uint) error {
 	// We use a transaction to first find the record to ensure it belongs to the user,
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormUsageRepository is the GORM implementation of the UsageRepository.
+type gormUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUsageRepository creates a new instance of gormUsageRepository.
+func NewGormUsageRepository(db *gorm.DB) repository.UsageRepository {
+	return &gormUsageRepository{db: db}
+}
+
+// Add upserts the usage row of the day, incrementing its counters atomically.
+func (r *gormUsageRepository) Add(ctx context.Context, userID uint, day time.Time, crawls, linkChecks int64) error {
+	usage := entity.DailyUsage{UserID: userID, Day: day, Crawls: crawls, LinkChecks: linkChecks}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"crawls":      gorm.Expr("crawls + ?", crawls),
+			"link_checks": gorm.Expr("link_checks + ?", linkChecks),
+			"updated_at":  time.Now(),
+		}),
+	}).Create(&usage).Error
+}
+
+// ReserveCrawl counts a crawl against the usage of the day if the limit allows it.
+func (r *gormUsageRepository) ReserveCrawl(ctx context.Context, userID uint, day time.Time, limit int64) (bool, error) {
+	granted, err := r.reserve(ctx, userID, day, "crawls", 1, limit)
+	return granted == 1, err
+}
+
+// ReserveLinkChecks counts as many of n link checks against the usage of the day as the limit allows.
+func (r *gormUsageRepository) ReserveLinkChecks(ctx context.Context, userID uint, day time.Time, n, limit int64) (int64, error) {
+	return r.reserve(ctx, userID, day, "link_checks", n, limit)
+}
+
+// reserve adds up to n to a counter of the day's usage row without taking it over limit, and
+// returns what was added. The row is locked while it is read and updated, so that concurrent
+// reservations can't both take what is left.
+func (r *gormUsageRepository) reserve(ctx context.Context, userID uint, day time.Time, column string, n, limit int64) (int64, error) {
+	var granted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists, so that there is something to lock.
+		empty := entity.DailyUsage{UserID: userID, Day: day}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&empty).Error; err != nil {
+			return err
+		}
+		var usage entity.DailyUsage
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND day = ?", userID, day).First(&usage).Error
+		if err != nil {
+			return err
+		}
+
+		used := usage.Crawls
+		if column == "link_checks" {
+			used = usage.LinkChecks
+		}
+		granted = n
+		if limit > 0 {
+			granted = min(n, max(limit-used, 0))
+		}
+		if granted == 0 {
+			return nil
+		}
+		return tx.Model(&usage).Update(column, gorm.Expr(column+" + ?", granted)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return granted, nil
+}
+
+// FindByDay retrieves the usage row of the day, or an empty one if the user consumed nothing yet.
+func (r *gormUsageRepository) FindByDay(ctx context.Context, userID uint, day time.Time) (*entity.DailyUsage, error) {
+	var usage entity.DailyUsage
+	err := r.db.WithContext(ctx).Where("user_id = ? AND day = ?", userID, day).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &entity.DailyUsage{UserID: userID, Day: day}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// Client represents a single WebSocket client (a user's browser).
+// Client represents a single WebSocket client (a tab of a user's browser).
 type Client struct {
 	UserID uint
 	Conn   *websocket.Conn
@@ -16,7 +16,7 @@ type Client struct {
 
 // Hub maintains the set of active clients and broadcasts messages.
 type Hub struct {
-	clients    map[uint]*Client // Map of userID to Client
+	clients    map[uint]map[*Client]struct{} // Map of userID to the set of their connected clients
 	mu         sync.RWMutex
 	register   chan *Client
 	unregister chan *Client
@@ -24,7 +24,7 @@ type Hub struct {
 
 func NewHub() *Hub {
 	return &Hub{
-		clients:    make(map[uint]*Client),
+		clients:    make(map[uint]map[*Client]struct{}),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
@@ -46,48 +46,59 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			h.clients[client.UserID] = client
+			if h.clients[client.UserID] == nil {
+				h.clients[client.UserID] = make(map[*Client]struct{})
+			}
+			h.clients[client.UserID][client] = struct{}{}
+			count := len(h.clients[client.UserID])
 			h.mu.Unlock()
-			log.Printf("Client registered for user ID: %d", client.UserID)
+			log.Printf("Client registered for user ID: %d (%d connected)", client.UserID, count)
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.UserID]; ok {
-				delete(h.clients, client.UserID)
+			// A client may be unregistered twice, e.g. on logout and again when its connection closes.
+			if _, ok := h.clients[client.UserID][client]; ok {
+				delete(h.clients[client.UserID], client)
+				if len(h.clients[client.UserID]) == 0 {
+					delete(h.clients, client.UserID)
+				}
 				close(client.Send)
 			}
+			count := len(h.clients[client.UserID])
 			h.mu.Unlock()
-			log.Printf("Client unregistered for user ID: %d", client.UserID)
+			log.Printf("Client unregistered for user ID: %d (%d connected)", client.UserID, count)
 		}
 	}
 }
 
-// Notify sends a message to a specific user if they are connected.
+// Notify sends a message to every connected client of a specific user.
 func (h *Hub) Notify(userID uint, message []byte) {
+	// Hold the lock while sending so that no client's channel is closed in the meantime.
 	h.mu.RLock()
-	client, ok := h.clients[userID]
-	h.mu.RUnlock()
+	defer h.mu.RUnlock()
 
-	if ok {
+	for client := range h.clients[userID] {
 		select {
 		case client.Send <- message:
 		default:
 			// If the send buffer is full, we might be sending too fast.
 			// For this app, we can just drop the message.
-			log.Printf("Message buffer full for user %d. Dropping message.", userID)
+			log.Printf("Message buffer full for a client of user %d. Dropping message.", userID)
 		}
 	}
 }
 
-// Disconnect finds a client by userID and triggers the unregister process.
+// Disconnect finds every client of a userID and triggers the unregister process.
 // This is called by the HTTP logout handler.
 func (h *Hub) Disconnect(userID uint) {
 	h.mu.RLock()
-	client, ok := h.clients[userID]
+	clients := make([]*Client, 0, len(h.clients[userID]))
+	for client := range h.clients[userID] {
+		clients = append(clients, client)
+	}
 	h.mu.RUnlock()
 
-	if ok {
-		// If the client exists, use the existing Unregister method
-		// to safely send them to the unregister channel.
+	// Use the existing Unregister method to safely send them to the unregister channel.
+	for _, client := range clients {
 		h.Unregister(client)
 	}
 }
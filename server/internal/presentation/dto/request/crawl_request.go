@@ -1,11 +1,132 @@
 package request
 
+import "github.com/diabahmed/sykell-crawler/internal/domain/entity"
+
 // CrawlRequest defines the structure for starting a new crawl.
 type CrawlRequest struct {
 	URL string `json:"url" binding:"required,url"`
+	CrawlSettings
+
+	// Reuse the result of an identical crawl completed this many minutes ago at most, instead of crawling.
+	ReuseWithinMinutes int `json:"reuse_within_minutes" binding:"omitempty,min=1,max=10080"`
+}
+
+// BatchCrawlRequest defines the structure for starting a crawl for each URL of a list.
+// URLs are validated one by one, so that invalid rows are rejected without failing the batch.
+type BatchCrawlRequest struct {
+	URLs []string `json:"urls" binding:"required,min=1"`
+	CrawlSettings
+}
+
+// CrawlSettings defines the optional settings of a crawl, shared by every crawl of a batch.
+type CrawlSettings struct {
+	Scope         string        `json:"scope" binding:"omitempty,oneof=host domain allowlist"`
+	AllowedHosts  []string      `json:"allowed_hosts" binding:"required_if=Scope allowlist,omitempty,max=100,dive,required,max=253"`
+	TrailingSlash string        `json:"trailing_slash" binding:"omitempty,oneof=strip add keep"`
+	Retry         *RetryRequest `json:"retry" binding:"omitempty"`
+
+	UserAgentPreset  string `json:"user_agent_preset" binding:"omitempty,oneof=chrome firefox safari mobile googlebot"`
+	UserAgent        string `json:"user_agent" binding:"omitempty,max=512"`
+	RequestTimeoutMs int    `json:"request_timeout_ms" binding:"omitempty,min=1000,max=120000"`
+	PageTimeoutMs    int    `json:"page_timeout_ms" binding:"omitempty,min=1000,max=300000"`
+	TimeoutSeconds   int    `json:"timeout_seconds" binding:"omitempty,min=10,max=86400"`
+	DelayMs          int    `json:"delay_ms" binding:"omitempty,min=0,max=10000"`
+	Proxy            string `json:"proxy" binding:"omitempty,oneof=direct pool"`
+	CheckSitemaps    bool   `json:"check_sitemaps"`
+	Mode             string `json:"mode" binding:"omitempty,oneof=page site"`
+	MaxPages         int    `json:"max_pages" binding:"omitempty,min=1,max=100000"`
+	MaxDepth         int    `json:"max_depth" binding:"omitempty,min=1,max=50"`
+	Priority         string `json:"priority" binding:"omitempty,oneof=interactive bulk"`
+
+	Credentials *CredentialsRequest `json:"credentials" binding:"omitempty"`
+}
+
+// CredentialsRequest defines the optional credentials of an authenticated crawl.
+// They are write-only: responses only report whether a crawl has credentials.
+type CredentialsRequest struct {
+	Headers      map[string]string `json:"headers" binding:"omitempty,max=50,dive,keys,required,max=256,endkeys,max=8192"`
+	Cookies      []CookieRequest   `json:"cookies" binding:"omitempty,max=50,dive"`
+	Username     string            `json:"username" binding:"omitempty,max=256"`
+	Password     string            `json:"password" binding:"omitempty,max=1024"`
+	BearerToken  string            `json:"bearer_token" binding:"omitempty,max=8192"`
+	ApplyToLinks bool              `json:"apply_to_links"`
+	ProxyURL     string            `json:"proxy_url" binding:"omitempty,max=2048,url,startswith=http://|startswith=https://|startswith=socks5://|startswith=socks5h://"`
+}
+
+// CookieRequest defines a single cookie sent with an authenticated crawl.
+type CookieRequest struct {
+	Name  string `json:"name" binding:"required,max=256"`
+	Value string `json:"value" binding:"max=4096"`
+}
+
+// RetryRequest defines the optional per-crawl overrides of the retry policy.
+type RetryRequest struct {
+	MaxAttempts      int      `json:"max_attempts" binding:"omitempty,min=1,max=10"`
+	InitialBackoffMs int      `json:"initial_backoff_ms" binding:"omitempty,min=1,max=60000"`
+	MaxBackoffMs     int      `json:"max_backoff_ms" binding:"omitempty,min=1,max=300000"`
+	StatusCodes      []int    `json:"status_codes" binding:"omitempty,max=20,dive,min=400,max=599"`
+	ErrorClasses     []string `json:"error_classes" binding:"omitempty,dive,oneof=timeout connection dns"`
+}
+
+// ToOptions converts the request's optional settings into the crawl options stored with the crawl.
+func (r CrawlSettings) ToOptions() entity.CrawlOptions {
+	opts := entity.CrawlOptions{
+		Scope:            r.Scope,
+		AllowedHosts:     r.AllowedHosts,
+		TrailingSlash:    r.TrailingSlash,
+		UserAgentPreset:  r.UserAgentPreset,
+		UserAgent:        r.UserAgent,
+		RequestTimeoutMs: r.RequestTimeoutMs,
+		PageTimeoutMs:    r.PageTimeoutMs,
+		TimeoutSeconds:   r.TimeoutSeconds,
+		DelayMs:          r.DelayMs,
+		Proxy:            r.Proxy,
+		CheckSitemaps:    r.CheckSitemaps,
+		Mode:             r.Mode,
+		MaxPages:         r.MaxPages,
+		MaxDepth:         r.MaxDepth,
+		Priority:         r.Priority,
+	}
+	if r.Retry != nil {
+		opts.Retry = &entity.RetryOptions{
+			MaxAttempts:      r.Retry.MaxAttempts,
+			InitialBackoffMs: r.Retry.InitialBackoffMs,
+			MaxBackoffMs:     r.Retry.MaxBackoffMs,
+			StatusCodes:      r.Retry.StatusCodes,
+			ErrorClasses:     r.Retry.ErrorClasses,
+		}
+	}
+	return opts
+}
+
+// ToCredentials converts the request's credentials for storage, or returns nil for anonymous crawls.
+func (r CrawlSettings) ToCredentials() *entity.CrawlCredentials {
+	if r.Credentials == nil {
+		return nil
+	}
+	creds := &entity.CrawlCredentials{
+		Headers:      r.Credentials.Headers,
+		Username:     r.Credentials.Username,
+		Password:     r.Credentials.Password,
+		BearerToken:  r.Credentials.BearerToken,
+		ApplyToLinks: r.Credentials.ApplyToLinks,
+		ProxyURL:     r.Credentials.ProxyURL,
+	}
+	for _, cookie := range r.Credentials.Cookies {
+		creds.Cookies = append(creds.Cookies, entity.CrawlCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return creds
 }
 
 // BulkDeleteRequest defines the structure for a bulk delete request.
 type BulkDeleteRequest struct {
 	IDs []uint `json:"ids" binding:"required,min=1"`
 }
+
+// CrawlLinksQuery defines the query parameters for listing the links of a crawl.
+type CrawlLinksQuery struct {
+	Type     string `form:"type" binding:"omitempty,oneof=internal external"`
+	Status   string `form:"status" binding:"omitempty"`
+	Page     int    `form:"page,default=1" binding:"min=1"`
+	PageSize int    `form:"page_size,default=50" binding:"min=1,max=500"`
+}
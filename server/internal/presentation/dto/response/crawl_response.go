@@ -0,0 +1,349 @@
+package response
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/application/service"
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+)
+
+// sitemapNamespace is the XML namespace of sitemaps and sitemap indexes.
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// CrawlLinksResponse defines the structure of a paginated list of crawl links.
+type CrawlLinksResponse struct {
+	Links    []entity.CrawlLink `json:"links"`
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+}
+
+// SitemapCrawlResponse defines the structure of the crawls started for the URLs of a sitemap.
+type SitemapCrawlResponse struct {
+	SitemapURL string          `json:"sitemap_url"`
+	URLCount   int             `json:"url_count"` // URLs found in the sitemap, which may exceed the crawls started
+	Crawls     []*entity.Crawl `json:"crawls"`
+}
+
+// BatchResponse defines the structure of a batch of crawls, with the outcome of every submitted row
+// in its results, and the progress of its crawls.
+type BatchResponse struct {
+	Batch    *entity.CrawlBatch `json:"batch"`
+	Progress BatchProgress      `json:"progress"`
+}
+
+// BatchProgress defines the aggregate progress of the crawls of a batch.
+type BatchProgress struct {
+	Total      int64   `json:"total"`
+	Pending    int64   `json:"pending"`
+	Processing int64   `json:"processing"`
+	Completed  int64   `json:"completed"`
+	Failed     int64   `json:"failed"`
+	Blocked    int64   `json:"blocked"`
+	TimedOut   int64   `json:"timed_out"`
+	Finished   int64   `json:"finished"` // Completed, failed, blocked or timed out
+	Percent    float64 `json:"percent"`  // Finished crawls out of all of them
+}
+
+// NewBatchProgress builds the progress of a batch from the number of its crawls per status.
+func NewBatchProgress(counts map[string]int64) BatchProgress {
+	progress := BatchProgress{
+		Pending:    counts["PENDING"],
+		Processing: counts["PROCESSING"],
+		Completed:  counts["COMPLETED"],
+		Failed:     counts["FAILED"],
+		Blocked:    counts["BLOCKED"],
+		TimedOut:   counts["TIMED_OUT"],
+	}
+	for _, count := range counts {
+		progress.Total += count
+	}
+	progress.Finished = progress.Completed + progress.Failed + progress.Blocked + progress.TimedOut
+	if progress.Total > 0 {
+		progress.Percent = math.Round(float64(progress.Finished)/float64(progress.Total)*1000) / 10
+	}
+	return progress
+}
+
+// UsageResponse defines the structure of a user's quota usage.
+type UsageResponse struct {
+	ConcurrentCrawls QuotaUsage `json:"concurrent_crawls"`
+	DailyCrawls      QuotaUsage `json:"daily_crawls"`
+	DailyLinkChecks  QuotaUsage `json:"daily_link_checks"`
+	MaxPagesPerCrawl *int       `json:"max_pages_per_crawl"` // Null when unlimited
+	ResetsAt         time.Time  `json:"resets_at"`           // When the daily quotas reset
+}
+
+// QuotaUsage defines the usage of a single quota; limit and remaining are null when it is unlimited.
+type QuotaUsage struct {
+	Used      int64  `json:"used"`
+	Limit     *int64 `json:"limit"`
+	Remaining *int64 `json:"remaining"`
+}
+
+// NewUsageResponse builds the usage response of a user.
+func NewUsageResponse(usage *service.Usage) UsageResponse {
+	resp := UsageResponse{
+		ConcurrentCrawls: newQuotaUsage(usage.ConcurrentCrawls, usage.Quota.MaxConcurrentCrawls),
+		DailyCrawls:      newQuotaUsage(usage.DailyCrawls, usage.Quota.DailyCrawls),
+		DailyLinkChecks:  newQuotaUsage(usage.DailyLinkChecks, usage.Quota.DailyLinkChecks),
+		ResetsAt:         usage.ResetsAt,
+	}
+	if usage.Quota.MaxPagesPerCrawl > 0 {
+		resp.MaxPagesPerCrawl = &usage.Quota.MaxPagesPerCrawl
+	}
+	return resp
+}
+
+func newQuotaUsage(used, limit int64) QuotaUsage {
+	quota := QuotaUsage{Used: used}
+	if limit > 0 {
+		remaining := service.Remaining(limit, used)
+		quota.Limit = &limit
+		quota.Remaining = &remaining
+	}
+	return quota
+}
+
+// SitemapURLSet defines the XML structure of a generated sitemap.
+type SitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+// SitemapURL defines a single entry of a generated sitemap.
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SitemapIndex defines the XML structure of a sitemap index listing the parts of a large sitemap.
+type SitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []SitemapRef `xml:"sitemap"`
+}
+
+// SitemapRef defines a single entry of a sitemap index.
+type SitemapRef struct {
+	Loc string `xml:"loc"`
+}
+
+// NewSitemapURLSet builds a sitemap listing pages, with lastmod taken from their Last-Modified header.
+func NewSitemapURLSet(pages []entity.CrawlPage) SitemapURLSet {
+	set := SitemapURLSet{Xmlns: sitemapNamespace, URLs: make([]SitemapURL, 0, len(pages))}
+	for _, page := range pages {
+		entry := SitemapURL{Loc: page.URL}
+		if page.LastModified != nil {
+			entry.LastMod = page.LastModified.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+	return set
+}
+
+// NewSitemapIndex builds a sitemap index listing the given sitemap URLs.
+func NewSitemapIndex(locs []string) SitemapIndex {
+	index := SitemapIndex{Xmlns: sitemapNamespace, Sitemaps: make([]SitemapRef, 0, len(locs))}
+	for _, loc := range locs {
+		index.Sitemaps = append(index.Sitemaps, SitemapRef{Loc: loc})
+	}
+	return index
+}
+
+// CrawlGraphResponse defines the JSON structure of the internal link graph of a site crawl.
+type CrawlGraphResponse struct {
+	Nodes    []GraphNode `json:"nodes"`
+	Edges    []GraphEdge `json:"edges"`
+	Orphans  []string    `json:"orphans"`   // Sitemap URLs no crawled page links to
+	DeadEnds []uint      `json:"dead_ends"` // IDs of the 200 HTML pages without internal links
+}
+
+// GraphNode defines a crawled page of a link graph with its metrics.
+type GraphNode struct {
+	ID         uint    `json:"id"`
+	URL        string  `json:"url"`
+	Title      string  `json:"title"`
+	StatusCode int     `json:"status_code"`
+	Depth      int     `json:"depth"`
+	Inlinks    int     `json:"inlinks"`
+	Outlinks   int     `json:"outlinks"`
+	PageRank   float64 `json:"pagerank"`
+	DeadEnd    bool    `json:"dead_end"`
+	Indexable  bool    `json:"indexable"`
+	Truncated  string  `json:"truncated,omitempty"` // The limits the page hit, so that it was analysed only in part
+}
+
+// GraphEdge defines a link between two nodes of a link graph.
+type GraphEdge struct {
+	Source     uint   `json:"source"`
+	Target     uint   `json:"target"`
+	AnchorText string `json:"anchor_text"`
+	Redirect   bool   `json:"redirect,omitempty"`
+}
+
+// NewCrawlGraphResponse builds the link graph of a site crawl, identifying pages by their ID.
+func NewCrawlGraphResponse(pages []entity.CrawlPage, edges []entity.CrawlEdge, orphans []string) CrawlGraphResponse {
+	graph := CrawlGraphResponse{
+		Nodes:    make([]GraphNode, 0, len(pages)),
+		Edges:    make([]GraphEdge, 0, len(edges)),
+		Orphans:  orphans,
+		DeadEnds: make([]uint, 0),
+	}
+	if graph.Orphans == nil {
+		graph.Orphans = make([]string, 0)
+	}
+	ids := make(map[string]uint, len(pages))
+	for _, page := range pages {
+		ids[page.NormalizedURL] = page.ID
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:         page.ID,
+			URL:        page.URL,
+			Title:      page.Title,
+			StatusCode: page.StatusCode,
+			Depth:      page.Depth,
+			Inlinks:    page.Inlinks,
+			Outlinks:   page.Outlinks,
+			PageRank:   page.PageRank,
+			DeadEnd:    page.DeadEnd,
+			Indexable:  page.Indexable,
+			Truncated:  page.Truncated,
+		})
+		if page.DeadEnd {
+			graph.DeadEnds = append(graph.DeadEnds, page.ID)
+		}
+	}
+	for _, edge := range edges {
+		source, sourceFound := ids[edge.SourceURL]
+		target, targetFound := ids[edge.TargetURL]
+		if !sourceFound || !targetFound {
+			continue
+		}
+		graph.Edges = append(graph.Edges, GraphEdge{Source: source, Target: target, AnchorText: edge.AnchorText, Redirect: edge.Redirect})
+	}
+	return graph
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (g CrawlGraphResponse) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph crawl {\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  n%d [label=%s, url=%s, status=%d, depth=%d, inlinks=%d, outlinks=%d, pagerank=%g, dead_end=%t, indexable=%t];\n",
+			node.ID, dotQuote(node.URL), dotQuote(node.URL), node.StatusCode, node.Depth, node.Inlinks, node.Outlinks, node.PageRank, node.DeadEnd, node.Indexable)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  n%d -> n%d [label=%s", edge.Source, edge.Target, dotQuote(edge.AnchorText))
+		if edge.Redirect {
+			b.WriteString(", style=dashed")
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote quotes s as a DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// graphMLNamespace is the XML namespace of GraphML documents.
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// GraphML defines the XML structure of a link graph in GraphML.
+type GraphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []GraphMLKey `xml:"key"`
+	Graph   GraphMLGraph `xml:"graph"`
+}
+
+// GraphMLKey declares an attribute of the nodes or edges of a GraphML graph.
+type GraphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// GraphMLGraph defines the nodes and edges of a GraphML graph.
+type GraphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []GraphMLNode `xml:"node"`
+	Edges       []GraphMLEdge `xml:"edge"`
+}
+
+// GraphMLNode defines a node of a GraphML graph.
+type GraphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []GraphMLData `xml:"data"`
+}
+
+// GraphMLEdge defines an edge of a GraphML graph.
+type GraphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []GraphMLData `xml:"data"`
+}
+
+// GraphMLData holds the value of a declared attribute.
+type GraphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GraphML converts the graph to GraphML.
+func (g CrawlGraphResponse) GraphML() GraphML {
+	doc := GraphML{
+		Xmlns: graphMLNamespace,
+		Keys: []GraphMLKey{
+			{ID: "url", For: "node", AttrName: "url", AttrType: "string"},
+			{ID: "title", For: "node", AttrName: "title", AttrType: "string"},
+			{ID: "status", For: "node", AttrName: "status_code", AttrType: "int"},
+			{ID: "depth", For: "node", AttrName: "depth", AttrType: "int"},
+			{ID: "inlinks", For: "node", AttrName: "inlinks", AttrType: "int"},
+			{ID: "outlinks", For: "node", AttrName: "outlinks", AttrType: "int"},
+			{ID: "pagerank", For: "node", AttrName: "pagerank", AttrType: "double"},
+			{ID: "dead_end", For: "node", AttrName: "dead_end", AttrType: "boolean"},
+			{ID: "indexable", For: "node", AttrName: "indexable", AttrType: "boolean"},
+			{ID: "anchor", For: "edge", AttrName: "anchor_text", AttrType: "string"},
+			{ID: "redirect", For: "edge", AttrName: "redirect", AttrType: "boolean"},
+		},
+		Graph: GraphMLGraph{ID: "crawl", EdgeDefault: "directed"},
+	}
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, GraphMLNode{
+			ID: fmt.Sprintf("n%d", node.ID),
+			Data: []GraphMLData{
+				{Key: "url", Value: node.URL},
+				{Key: "title", Value: node.Title},
+				{Key: "status", Value: strconv.Itoa(node.StatusCode)},
+				{Key: "depth", Value: strconv.Itoa(node.Depth)},
+				{Key: "inlinks", Value: strconv.Itoa(node.Inlinks)},
+				{Key: "outlinks", Value: strconv.Itoa(node.Outlinks)},
+				{Key: "pagerank", Value: strconv.FormatFloat(node.PageRank, 'g', -1, 64)},
+				{Key: "dead_end", Value: strconv.FormatBool(node.DeadEnd)},
+				{Key: "indexable", Value: strconv.FormatBool(node.Indexable)},
+			},
+		})
+	}
+	for _, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, GraphMLEdge{
+			Source: fmt.Sprintf("n%d", edge.Source),
+			Target: fmt.Sprintf("n%d", edge.Target),
+			Data: []GraphMLData{
+				{Key: "anchor", Value: edge.AnchorText},
+				{Key: "redirect", Value: strconv.FormatBool(edge.Redirect)},
+			},
+		})
+	}
+	return doc
+}
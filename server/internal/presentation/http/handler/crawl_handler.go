@@ -1,12 +1,30 @@
 package handler
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/diabahmed/sykell-crawler/internal/application/service"
+	"github.com/diabahmed/sykell-crawler/internal/domain/entity"
+	"github.com/diabahmed/sykell-crawler/internal/domain/repository"
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
 	"github.com/diabahmed/sykell-crawler/internal/presentation/dto/request"
+	"github.com/diabahmed/sykell-crawler/internal/presentation/dto/response"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type CrawlHandler struct {
@@ -19,14 +37,15 @@ func NewCrawlHandler(crawlService service.CrawlService) *CrawlHandler {
 
 // StartCrawl godoc
 // @Summary      Start a new crawl job
-// @Description  Submits a URL to be crawled. The job is processed in the background.
+// @Description  Submits a URL to be crawled. The job is processed in the background. Identical crawls running at the same time share one execution, and with reuse_within_minutes an anonymous crawl copies the result of an identical crawl completed within that time instead, returning it completed with reused_from_id set.
 // @Tags         Crawling
 // @Accept       json
 // @Produce      json
-// @Param        url body request.CrawlRequest true "URL to Crawl"
+// @Param        url body request.CrawlRequest true "URL to Crawl and optional crawl settings"
 // @Success      202  {object}  entity.Crawl
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
 // @Router       /crawls [post]
@@ -40,7 +59,17 @@ func (h *CrawlHandler) StartCrawl(c *gin.Context) {
 	// Retrieve userID from the context (set by the auth middleware)
 	userID := c.MustGet("userID").(uint)
 
-	crawl, err := h.crawlService.StartCrawl(c.Request.Context(), userID, req.URL)
+	reuseWithin := time.Duration(req.ReuseWithinMinutes) * time.Minute
+	crawl, err := h.crawlService.StartCrawl(c.Request.Context(), userID, req.URL, req.ToOptions(), req.ToCredentials(), reuseWithin)
+	h.setQuotaHeaders(c, userID)
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrCredentialsUnavailable) || errors.Is(err, crawler.ErrNoProxies) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start crawl"})
 		return
@@ -49,6 +78,252 @@ func (h *CrawlHandler) StartCrawl(c *gin.Context) {
 	c.JSON(http.StatusAccepted, crawl)
 }
 
+// StartSitemapCrawls godoc
+// @Summary      Crawl every URL of a sitemap
+// @Description  Fetches a sitemap, following sitemap indexes, and starts a crawl with the given settings for each URL it lists (at most 1000).
+// @Tags         Crawling
+// @Accept       json
+// @Produce      json
+// @Param        url body request.CrawlRequest true "Sitemap URL and optional crawl settings applied to every crawl"
+// @Success      202  {object}  response.SitemapCrawlResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      422  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /crawls/sitemap [post]
+func (h *CrawlHandler) StartSitemapCrawls(c *gin.Context) {
+	var req request.CrawlRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.MustGet("userID").(uint)
+
+	crawls, total, err := h.crawlService.StartSitemapCrawls(c.Request.Context(), userID, req.URL, req.ToOptions(), req.ToCredentials())
+	h.setQuotaHeaders(c, userID)
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		// Crawls started before the quota ran out keep running.
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "crawls": crawls})
+		return
+	}
+	if errors.Is(err, service.ErrCredentialsUnavailable) || errors.Is(err, crawler.ErrNoProxies) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, crawler.ErrInvalidSitemap) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sitemap crawls"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response.SitemapCrawlResponse{
+		SitemapURL: req.URL,
+		URLCount:   total,
+		Crawls:     crawls,
+	})
+}
+
+// maxBatchUploadSize caps the size of a batch submitted as a file upload.
+const maxBatchUploadSize = 5 << 20
+
+// StartBatchCrawls godoc
+// @Summary      Crawl a list of URLs
+// @Description  Starts a crawl with the same settings for every URL of a list, submitted as JSON or as a CSV or TXT file upload (at most 5000 URLs). Every URL is validated and duplicates are dropped; the outcome of each row is reported in the batch's results, which can be polled for progress.
+// @Tags         Crawling
+// @Accept       json
+// @Accept       mpfd
+// @Produce      json
+// @Param        batch     body      request.BatchCrawlRequest  false  "URLs and optional crawl settings applied to every crawl"
+// @Param        file      formData  file    false  "CSV file with a url column or the URLs in its first column, or TXT file with one URL per line"
+// @Param        settings  formData  string  false  "Optional crawl settings as JSON, for file uploads"
+// @Success      202  {object}  response.BatchResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /crawls/batch [post]
+func (h *CrawlHandler) StartBatchCrawls(c *gin.Context) {
+	var settings request.CrawlSettings
+	var rows []entity.BatchRow
+	batch := &entity.CrawlBatch{Source: "json"}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchUploadSize)
+		file, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("batch files may be at most %d bytes", maxBatchUploadSize)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a CSV or TXT file is required"})
+			return
+		}
+		if raw := c.PostForm("settings"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settings: " + err.Error()})
+				return
+			}
+			if err := binding.Validator.ValidateStruct(&settings); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		batch.FileName = filepath.Base(file.Filename)
+		batch.Source = batchFileSource(file)
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the uploaded file"})
+			return
+		}
+		defer f.Close()
+		if batch.Source == "csv" {
+			rows, err = readBatchCSV(f)
+		} else {
+			rows, err = readBatchText(f)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse the uploaded file: " + err.Error()})
+			return
+		}
+	} else {
+		var req request.BatchCrawlRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings = req.CrawlSettings
+		for i, u := range req.URLs {
+			rows = append(rows, entity.BatchRow{Row: i + 1, URL: u})
+		}
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the batch lists no URLs"})
+		return
+	}
+
+	userID := c.MustGet("userID").(uint)
+
+	err := h.crawlService.StartBatch(c.Request.Context(), userID, batch, rows, settings.ToOptions(), settings.ToCredentials())
+	h.setQuotaHeaders(c, userID)
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrBatchTooLarge) || errors.Is(err, service.ErrCredentialsUnavailable) || errors.Is(err, crawler.ErrNoProxies) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start batch"})
+		return
+	}
+
+	// Nothing has run yet, so every accepted crawl is pending.
+	c.JSON(http.StatusAccepted, response.BatchResponse{
+		Batch:    batch,
+		Progress: response.NewBatchProgress(map[string]int64{"PENDING": int64(batch.Accepted)}),
+	})
+}
+
+// batchFileSource tells a CSV upload from a plain text one by its extension or content type.
+func batchFileSource(file *multipart.FileHeader) string {
+	if strings.EqualFold(filepath.Ext(file.Filename), ".csv") || strings.HasPrefix(file.Header.Get("Content-Type"), "text/csv") {
+		return "csv"
+	}
+	return "txt"
+}
+
+// readBatchCSV reads the URLs of a CSV file: the "url" column when the first row names one,
+// the first column otherwise. Rows are numbered by their line in the file.
+func readBatchCSV(r io.Reader) ([]entity.BatchRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var rows []entity.BatchRow
+	column := 0
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+			if i := slices.IndexFunc(record, func(field string) bool { return strings.EqualFold(strings.TrimSpace(field), "url") }); i >= 0 {
+				column = i
+				continue
+			}
+		}
+		// Rows without a URL are kept, so that they are reported as rejected.
+		row := entity.BatchRow{}
+		row.Row, _ = reader.FieldPos(0)
+		if column < len(record) {
+			row.URL = record[column]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readBatchText reads the URLs of a text file, one per line. Blank lines and lines starting
+// with # are skipped; rows are numbered by their line in the file.
+func readBatchText(r io.Reader) ([]entity.BatchRow, error) {
+	var rows []entity.BatchRow
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rows = append(rows, entity.BatchRow{Row: line, URL: text})
+	}
+	return rows, scanner.Err()
+}
+
+// GetBatch godoc
+// @Summary      Get a batch of crawls
+// @Description  Retrieves a batch with the outcome of every submitted row and the aggregate progress of its crawls.
+// @Tags         Crawling
+// @Produce      json
+// @Param        id   path      int  true  "Batch ID"
+// @Success      200  {object}  response.BatchResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /crawls/batch/{id} [get]
+func (h *CrawlHandler) GetBatch(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch ID"})
+		return
+	}
+
+	batch, counts, err := h.crawlService.GetBatch(c.Request.Context(), uint(batchID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.BatchResponse{Batch: batch, Progress: response.NewBatchProgress(counts)})
+}
+
 // GetCrawlHistory godoc
 // @Summary      Get user's crawl history
 // @Description  Retrieves a list of all crawl jobs initiated by the logged-in user.
@@ -101,6 +376,238 @@ func (h *CrawlHandler) GetCrawlResult(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// linkStatusFilter matches the accepted values of the "status" query parameter on the links endpoint.
+var linkStatusFilter = regexp.MustCompile(`^(ok|redirect|broken|error|blocked|[1-5][0-9]{2})$`)
+
+// GetCrawlLinks godoc
+// @Summary      List the links of a crawl
+// @Description  Retrieves every link found by a crawl with its check result, filtered by type and status and paginated.
+// @Tags         Crawling
+// @Produce      json
+// @Param        id         path      int     true   "Crawl ID"
+// @Param        type       query     string  false  "Link type"  Enums(internal, external)
+// @Param        status     query     string  false  "ok, redirect, broken, error, blocked or an exact status code"
+// @Param        page       query     int     false  "Page number"  default(1)
+// @Param        page_size  query     int     false  "Links per page (max 500)"  default(50)
+// @Success      200  {object}  response.CrawlLinksResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /crawls/{id}/links [get]
+func (h *CrawlHandler) GetCrawlLinks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	crawlID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid crawl ID"})
+		return
+	}
+
+	var query request.CrawlLinksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Status != "" && !linkStatusFilter.MatchString(query.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status filter"})
+		return
+	}
+
+	filter := repository.CrawlLinkFilter{
+		LinkType: query.Type,
+		Status:   query.Status,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}
+	links, total, err := h.crawlService.GetCrawlLinks(c.Request.Context(), uint(crawlID), userID, filter)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "crawl result not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.CrawlLinksResponse{
+		Links:    links,
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	})
+}
+
+// sitemapMaxURLs is the number of URLs a single sitemap may list; larger sitemaps are split into parts.
+const sitemapMaxURLs = 50000
+
+// GetCrawlSitemap godoc
+// @Summary      Download the sitemap of a site crawl
+// @Description  Generates a sitemap.xml of the indexable 200 pages found by a completed site crawl, with lastmod taken from their Last-Modified headers. Above 50,000 URLs a sitemap index is returned instead, listing the parts to fetch with the part parameter.
+// @Tags         Crawling
+// @Produce      xml
+// @Param        id    path      int  true   "Crawl ID"
+// @Param        part  query     int  false  "1-based part of a sitemap split by its index"
+// @Success      200  {object}  response.SitemapURLSet
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /crawls/{id}/sitemap.xml [get]
+func (h *CrawlHandler) GetCrawlSitemap(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	crawlID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid crawl ID"})
+		return
+	}
+	part := 0
+	if raw := c.Query("part"); raw != "" {
+		if part, err = strconv.Atoi(raw); err != nil || part < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sitemap part"})
+			return
+		}
+	}
+
+	pages, err := h.crawlService.GetSitemapPages(c.Request.Context(), uint(crawlID), userID)
+	if errors.Is(err, service.ErrNotSiteCrawl) || errors.Is(err, service.ErrCrawlNotCompleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "crawl result not found"})
+		return
+	}
+
+	parts := max((len(pages)+sitemapMaxURLs-1)/sitemapMaxURLs, 1)
+	if part == 0 && parts > 1 {
+		base := requestURL(c)
+		locs := make([]string, parts)
+		for i := range locs {
+			locs[i] = fmt.Sprintf("%s?part=%d", base, i+1)
+		}
+		renderXML(c, response.NewSitemapIndex(locs))
+		return
+	}
+	if part > parts {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sitemap part not found"})
+		return
+	}
+	start := max(part-1, 0) * sitemapMaxURLs
+	renderXML(c, response.NewSitemapURLSet(pages[start:min(start+sitemapMaxURLs, len(pages))]))
+}
+
+// GetCrawlGraph godoc
+// @Summary      Get the link graph of a site crawl
+// @Description  Returns the internal link graph of a completed site crawl: its pages with their inlink and outlink counts, crawl depth and internal PageRank, the links between them with their anchor text, orphan pages (listed in a sitemap but not linked) and dead ends. The graph is returned as JSON, GraphML or Graphviz DOT.
+// @Tags         Crawling
+// @Produce      json
+// @Produce      xml
+// @Produce      plain
+// @Param        id      path      int     true   "Crawl ID"
+// @Param        format  query     string  false  "json (default), graphml or dot"
+// @Success      200  {object}  response.CrawlGraphResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /crawls/{id}/graph [get]
+func (h *CrawlHandler) GetCrawlGraph(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	crawlID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid crawl ID"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "graphml" && format != "dot" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, graphml, dot"})
+		return
+	}
+
+	crawlGraph, err := h.crawlService.GetCrawlGraph(c.Request.Context(), uint(crawlID), userID)
+	if errors.Is(err, service.ErrNotSiteCrawl) || errors.Is(err, service.ErrCrawlNotCompleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "crawl result not found"})
+		return
+	}
+
+	graph := response.NewCrawlGraphResponse(crawlGraph.Pages, crawlGraph.Edges, crawlGraph.Orphans)
+	switch format {
+	case "graphml":
+		renderXML(c, graph.GraphML())
+	case "dot":
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(graph.DOT()))
+	default:
+		c.JSON(http.StatusOK, graph)
+	}
+}
+
+// requestURL reconstructs the absolute URL of the current request, without its query.
+func requestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	// The header is only believed from a trusted proxy, see middleware.TrustedProxyMiddleware.
+	if proto := c.GetHeader("X-Forwarded-Proto"); c.GetBool("fromTrustedProxy") && (proto == "http" || proto == "https") {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.Path
+}
+
+// renderXML writes v as an XML document with its declaration.
+func renderXML(c *gin.Context, v any) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render XML"})
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// GetUsage godoc
+// @Summary      Get the user's quota usage
+// @Description  Reports how much of each crawling quota the logged-in user has used and has left. Daily quotas reset at midnight UTC.
+// @Tags         Crawling
+// @Produce      json
+// @Success      200  {object}  response.UsageResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /usage [get]
+func (h *CrawlHandler) GetUsage(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	usage, err := h.crawlService.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.NewUsageResponse(usage))
+}
+
+// setQuotaHeaders reports the quotas the user has left in the response headers. Unlimited quotas
+// are left out.
+func (h *CrawlHandler) setQuotaHeaders(c *gin.Context, userID uint) {
+	usage, err := h.crawlService.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		return
+	}
+	remaining := map[string]int64{
+		"X-Quota-Concurrent-Remaining":        service.Remaining(usage.Quota.MaxConcurrentCrawls, usage.ConcurrentCrawls),
+		"X-Quota-Daily-Crawls-Remaining":      service.Remaining(usage.Quota.DailyCrawls, usage.DailyCrawls),
+		"X-Quota-Daily-Link-Checks-Remaining": service.Remaining(usage.Quota.DailyLinkChecks, usage.DailyLinkChecks),
+	}
+	for header, value := range remaining {
+		if value >= 0 {
+			c.Header(header, strconv.FormatInt(value, 10))
+		}
+	}
+	c.Header("X-Quota-Reset", strconv.FormatInt(usage.ResetsAt.Unix(), 10))
+}
+
 // RerunCrawl handles the request to re-run a crawl.
 func (h *CrawlHandler) RerunCrawl(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
@@ -112,6 +619,15 @@ func (h *CrawlHandler) RerunCrawl(c *gin.Context) {
 
 	// The service now handles the update logic.
 	updatedCrawl, err := h.crawlService.RerunCrawl(c.Request.Context(), uint(crawlID), userID)
+	h.setQuotaHeaders(c, userID)
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrCredentialsUnavailable) || errors.Is(err, crawler.ErrNoProxies) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "crawl not found or permission denied"})
 		return
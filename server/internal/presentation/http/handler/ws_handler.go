@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/diabahmed/sykell-crawler/internal/application/service"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/websockets"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -44,6 +45,17 @@ func (h *WSHandler) ServeWs(c *gin.Context) {
 	go h.readPump(client)
 }
 
+// GetEventSchema godoc
+// @Summary      Get the schema of websocket events
+// @Description  Returns the JSON schema of the events sent over the websocket: their common envelope and the payload of every event type.
+// @Tags         Realtime
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Router       /events/schema [get]
+func (h *WSHandler) GetEventSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", service.EventSchema)
+}
+
 // writePump pumps messages from the hub to the websocket connection.
 func (h *WSHandler) writePump(client *websockets.Client) {
 	defer func() {
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/crawler"
+	"github.com/gin-gonic/gin"
+)
+
+// TrustedProxyMiddleware creates a Gin middleware that marks requests sent by one of the trusted
+// proxies (IP addresses or CIDRs), so that handlers only believe X-Forwarded-* headers from them.
+// The router must be given the same list with SetTrustedProxies for ClientIP to agree.
+func TrustedProxyMiddleware(proxies []string) (gin.HandlerFunc, error) {
+	prefixes, err := crawler.ParsePrefixes(proxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+
+	return func(c *gin.Context) {
+		if addr, err := netip.ParseAddr(c.RemoteIP()); err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					c.Set("fromTrustedProxy", true)
+					break
+				}
+			}
+		}
+		c.Next()
+	}, nil
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc returns the client a request is counted against.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP counts requests against the client's IP address.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser counts requests against the logged-in user, falling back to the IP address. It must
+// run after AuthMiddleware.
+func KeyByUser(c *gin.Context) string {
+	if userID, ok := c.Get("userID"); ok {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return KeyByIP(c)
+}
+
+// RateLimitMiddleware creates a Gin middleware that limits the requests each client makes to a
+// route group with a token bucket. Every response carries the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers; rejected requests also get Retry-After.
+// Requests are let through when the store fails, so an outage doesn't take the API down.
+func RateLimitMiddleware(store ratelimit.Store, group string, limit ratelimit.Limit, key RateLimitKeyFunc) gin.HandlerFunc {
+	if !limit.Enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(math.Ceil(limit.Window.Seconds())))
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions { // let CORS pre‑flights through
+			c.Next()
+			return
+		}
+
+		result, err := store.Take(c.Request.Context(), group+":"+key(c), limit)
+		if err != nil {
+			log.Printf("Error checking rate limit of group %s: %v", group, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", policy)
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
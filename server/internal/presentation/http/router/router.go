@@ -5,6 +5,7 @@ import (
 
 	"github.com/diabahmed/sykell-crawler/internal/application/service"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/auth"
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/ratelimit"
 	"github.com/diabahmed/sykell-crawler/internal/infrastructure/websockets"
 	"github.com/diabahmed/sykell-crawler/internal/presentation/http/handler"
 	"github.com/diabahmed/sykell-crawler/internal/presentation/http/middleware"
@@ -14,22 +15,47 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// RateLimits are the request limits of each route group; a zero limit disables it.
+type RateLimits struct {
+	Auth   ratelimit.Limit // Login and registration, per IP address
+	Submit ratelimit.Limit // Starting and re-running crawls, per user
+	API    ratelimit.Limit // Every other authenticated route, per user
+}
+
 func NewRouter(
 	userService service.UserService,
 	crawlService service.CrawlService,
 	tokenManager auth.TokenManager,
 	hub *websockets.Hub,
-) *gin.Engine {
+	limiter ratelimit.Store,
+	limits RateLimits,
+	trustedProxies []string,
+) (*gin.Engine, error) {
 	router := gin.Default()
 
+	// Only believe X-Forwarded-For and X-Forwarded-Proto from our own proxies; otherwise any
+	// client could pick the IP address its requests are rate limited by.
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	trustedProxy, err := middleware.TrustedProxyMiddleware(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	router.Use(trustedProxy)
+
 	// --- CONFIGURE AND APPLY CORS MIDDLEWARE ---
 	// This configuration is permissive for local development.
 	// For production, we should restrict origins to our frontend's domain.
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "Origin"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins: []string{"http://localhost:3000"},
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowHeaders: []string{"Accept", "Authorization", "Content-Type", "Origin"},
+		ExposeHeaders: []string{
+			"Content-Length",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+			"X-Quota-Concurrent-Remaining", "X-Quota-Daily-Crawls-Remaining", "X-Quota-Daily-Link-Checks-Remaining", "X-Quota-Reset",
+		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -41,31 +67,49 @@ func NewRouter(
 	crawlHandler := handler.NewCrawlHandler(crawlService)
 	wsHandler := handler.NewWSHandler(hub)
 
+	authMiddleware := middleware.AuthMiddleware(tokenManager)
+	authLimit := middleware.RateLimitMiddleware(limiter, "auth", limits.Auth, middleware.KeyByIP)
+	submitLimit := middleware.RateLimitMiddleware(limiter, "submit", limits.Submit, middleware.KeyByUser)
+	apiLimit := middleware.RateLimitMiddleware(limiter, "api", limits.API, middleware.KeyByUser)
+
 	// Group routes under /api/v1
 	v1 := router.Group("/api/v1")
 	{
 		// Auth routes are public except for logout
 		authRoutes := v1.Group("/auth")
 		{
-			authRoutes.POST("/register", authHandler.Register)
-			authRoutes.POST("/login", authHandler.Login)
-			authRoutes.POST("/logout", middleware.AuthMiddleware(tokenManager), authHandler.Logout)
-			authRoutes.GET("/me", middleware.AuthMiddleware(tokenManager), authHandler.GetMe)
+			authRoutes.POST("/register", authLimit, authHandler.Register)
+			authRoutes.POST("/login", authLimit, authHandler.Login)
+			authRoutes.POST("/logout", authMiddleware, apiLimit, authHandler.Logout)
+			authRoutes.GET("/me", authMiddleware, apiLimit, authHandler.GetMe)
+		}
+
+		// Crawl submissions are limited separately, as each of them starts crawling
+		submitRoutes := v1.Group("/crawls").Use(authMiddleware, submitLimit)
+		{
+			submitRoutes.POST("", crawlHandler.StartCrawl)
+			submitRoutes.POST("/sitemap", crawlHandler.StartSitemapCrawls)
+			submitRoutes.POST("/batch", crawlHandler.StartBatchCrawls)
+			submitRoutes.POST("/:id/rerun", crawlHandler.RerunCrawl)
 		}
 
 		// Crawl routes are protected by the auth middleware
-		crawlRoutes := v1.Group("/crawls").Use(middleware.AuthMiddleware(tokenManager))
+		crawlRoutes := v1.Group("/crawls").Use(authMiddleware, apiLimit)
 		{
-			crawlRoutes.POST("", crawlHandler.StartCrawl)
+			crawlRoutes.GET("/batch/:id", crawlHandler.GetBatch)
 			crawlRoutes.GET("", crawlHandler.GetCrawlHistory)
 			crawlRoutes.GET("/:id", crawlHandler.GetCrawlResult)
-			crawlRoutes.POST("/:id/rerun", crawlHandler.RerunCrawl)
+			crawlRoutes.GET("/:id/links", crawlHandler.GetCrawlLinks)
+			crawlRoutes.GET("/:id/sitemap.xml", crawlHandler.GetCrawlSitemap)
+			crawlRoutes.GET("/:id/graph", crawlHandler.GetCrawlGraph)
 			crawlRoutes.DELETE("/:id", crawlHandler.DeleteCrawl)
 			crawlRoutes.DELETE("/bulk", crawlHandler.DeleteCrawlsBulk)
 		}
 
-		v1.GET("/ws", middleware.AuthMiddleware(tokenManager), wsHandler.ServeWs)
+		v1.GET("/usage", authMiddleware, apiLimit, crawlHandler.GetUsage)
+		v1.GET("/ws", authMiddleware, apiLimit, wsHandler.ServeWs)
+		v1.GET("/events/schema", wsHandler.GetEventSchema)
 	}
 
-	return router
+	return router, nil
 }
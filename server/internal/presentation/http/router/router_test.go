@@ -0,0 +1,63 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/diabahmed/sykell-crawler/internal/infrastructure/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// login sends a login request from remoteAddr claiming to be forwarded for forwardedFor.
+func login(t *testing.T, r *gin.Engine, remoteAddr, forwardedFor string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limits := RateLimits{Auth: ratelimit.Limit{Requests: 2, Window: time.Minute}}
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		wantLimited    bool // Whether the third request, from a new forwarded address, is limited
+	}{
+		{"no trusted proxies", nil, true},
+		{"trusted proxy", []string{"192.0.2.0/24"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRouter(nil, nil, nil, nil, ratelimit.NewMemoryStore(), limits, tt.trustedProxies)
+			if err != nil {
+				t.Fatalf("NewRouter: %v", err)
+			}
+			for i := range 2 {
+				if code := login(t, r, "192.0.2.1:1234", fmt.Sprintf("203.0.113.%d", i)); code == http.StatusTooManyRequests {
+					t.Fatalf("request %d was limited", i+1)
+				}
+			}
+			limited := login(t, r, "192.0.2.1:1234", "203.0.113.99") == http.StatusTooManyRequests
+			if limited != tt.wantLimited {
+				t.Errorf("third request limited = %v, want %v", limited, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestNewRouterRejectsInvalidTrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if _, err := NewRouter(nil, nil, nil, nil, ratelimit.NewMemoryStore(), RateLimits{}, []string{"not-an-ip"}); err == nil {
+		t.Error("NewRouter accepted an invalid trusted proxy")
+	}
+}
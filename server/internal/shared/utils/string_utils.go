@@ -0,0 +1,14 @@
+package utils
+
+// Truncate cuts s to at most max characters, never splitting a multi-byte character.
+// MySQL sizes varchar columns in characters, so this keeps a value within its column.
+func Truncate(s string, max int) string {
+	count := 0
+	for i := range s {
+		if count == max {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"text/html", 100, "text/html"},
+		{"text/html", 4, "text"},
+		{"héllo", 2, "hé"},
+		{"日本語", 3, "日本語"},
+		{"日本語", 0, ""},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.max); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}